@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sql/pkg/bcel/functions"
@@ -15,10 +19,56 @@ import (
 )
 
 type Env struct {
-	celEnv *cel.Env
+	celEnv                 *cel.Env
+	caseInsensitiveColumns bool
+	lookups                LookupProvider
+	functionDefs           map[string]string
+	functions              []*userFunction
 }
 
-func NewEnv(ctx context.Context) (*Env, error) {
+// LookupProvider serves the rows of named lookup tables to the lookup() function.
+type LookupProvider interface {
+	// Load makes sure the named table is loaded and current before an expression that uses it is evaluated.
+	Load(ctx context.Context, name string) error
+
+	// Lookup returns the row of a loaded table with the given key, or nil if there is no such row.
+	Lookup(name string, key any) (map[string]any, error)
+}
+
+// EnvOption configures optional behavior of an Env.
+type EnvOption func(*Env)
+
+// WithCaseInsensitiveColumns lower-cases column references in expressions, so `.USER_ID` and `.user_id` both
+// resolve to the lower-cased column name. Query results must use lower-cased column names.
+func WithCaseInsensitiveColumns() EnvOption {
+	return func(e *Env) {
+		e.caseInsensitiveColumns = true
+	}
+}
+
+// WithLookups enables the `lookup('table', key)` function, which returns the row of a lookup table with the given key
+// as a map, or an empty map if there is no such row. The table name must be a string literal.
+func WithLookups(provider LookupProvider) EnvOption {
+	return func(e *Env) {
+		e.lookups = provider
+	}
+}
+
+// WithFunctions defines named, parameterized expressions that can be called from any expression, keyed by their
+// signature, e.g. `normLogin(s)` with the body `toLower(trim(s))`. Calls are expanded inline when an expression is
+// compiled. NewEnv returns an error if a function is recursive.
+func WithFunctions(defs map[string]string) EnvOption {
+	return func(e *Env) {
+		e.functionDefs = defs
+	}
+}
+
+func NewEnv(ctx context.Context, opts ...EnvOption) (*Env, error) {
+	ret := &Env{}
+	for _, opt := range opts {
+		opt(ret)
+	}
+
 	var celOpts []cel.EnvOption
 
 	// CEL variables
@@ -27,31 +77,163 @@ func NewEnv(ctx context.Context) (*Env, error) {
 		cel.Variable("resource", cel.MapType(types.StringType, types.StringType)),
 		cel.Variable("principal", cel.MapType(types.StringType, types.StringType)),
 		cel.Variable("entitlement", cel.MapType(types.StringType, types.StringType)),
+		cel.Variable("grant", cel.MapType(types.StringType, types.DynType)),
+	)
+
+	// CEL extension libraries
+	celOpts = append(celOpts,
+		ext.Strings(),
+		ext.Lists(),
+		ext.Sets(),
+		ext.Math(),
+		ext.Encoders(),
 	)
 
 	// CEL functions
 	celOpts = append(celOpts, functions.GetAllOptions()...)
 
+	if ret.lookups != nil {
+		celOpts = append(celOpts, lookupFunction(ret.lookups))
+	}
+
 	celEnv, err := cel.NewEnv(celOpts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Env{
-		celEnv: celEnv,
-	}, nil
+
+	if len(ret.functionDefs) > 0 {
+		ret.functions, err = newUserFunctions(celEnv, ret.functionDefs, ret.caseInsensitiveColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		celEnv, err = withUserFunctions(celEnv, ret.functions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ret.celEnv = celEnv
+
+	return ret, nil
+}
+
+func lookupFunction(provider LookupProvider) cel.EnvOption {
+	return cel.Function("lookup",
+		cel.Overload(
+			"lookup_string_dyn",
+			[]*cel.Type{cel.StringType, cel.DynType},
+			cel.MapType(cel.StringType, cel.DynType),
+			cel.BinaryBinding(func(lhs ref.Val, rhs ref.Val) ref.Val {
+				name, ok := lhs.(types.String)
+				if !ok {
+					return types.MaybeNoSuchOverloadErr(lhs)
+				}
+
+				var key any
+				if rhs.Type() != types.NullType {
+					key = rhs.Value()
+				}
+
+				row, err := provider.Lookup(string(name), key)
+				if err != nil {
+					return types.WrapErr(err)
+				}
+
+				if row == nil {
+					row = make(map[string]any)
+				}
+				return types.DefaultTypeAdapter.NativeToValue(row)
+			}),
+		),
+	)
 }
 
 func (t *Env) Evaluate(ctx context.Context, expr string, inputs map[string]any) (any, error) {
+	out, err := t.eval(ctx, expr, inputs)
+	if err != nil {
+		return "", err
+	}
+
+	return out.Value(), nil
+}
+
+// EvaluateValue evaluates expr and converts the result to a protobuf value, keeping its type. Lists and maps with
+// string keys become nested values, numbers become doubles, timestamps become RFC 3339 strings and bytes become
+// base64 strings.
+func (t *Env) EvaluateValue(ctx context.Context, expr string, inputs map[string]any) (*structpb.Value, error) {
+	out, err := t.eval(ctx, expr, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return toValue(out)
+}
+
+// EvaluateExactValue evaluates expr like EvaluateValue, except that integers are returned as decimal strings, so values
+// above 2^53 are kept exactly. It also returns the CEL type name of the result, such as "int" or "double".
+func (t *Env) EvaluateExactValue(ctx context.Context, expr string, inputs map[string]any) (*structpb.Value, string, error) {
+	out, err := t.eval(ctx, expr, inputs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	typeName := out.Type().TypeName()
+	switch v := out.(type) {
+	case types.Int:
+		return structpb.NewStringValue(strconv.FormatInt(int64(v), 10)), typeName, nil
+	case types.Uint:
+		return structpb.NewStringValue(strconv.FormatUint(uint64(v), 10)), typeName, nil
+	}
+
+	ret, err := toValue(out)
+	if err != nil {
+		return nil, "", err
+	}
+	return ret, typeName, nil
+}
+
+func toValue(out ref.Val) (*structpb.Value, error) {
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, err
+	}
+
+	ret, ok := native.(*structpb.Value)
+	if !ok {
+		return nil, fmt.Errorf("unexpected conversion result %T", native)
+	}
+	return ret, nil
+}
+
+func (t *Env) eval(ctx context.Context, expr string, inputs map[string]any) (ref.Val, error) {
 	expr = preprocessExpressions(expr)
+	if t.caseInsensitiveColumns {
+		expr = lowerColumnAccess(expr)
+	}
 
 	ast, issues := t.celEnv.Compile(expr)
 	if issues != nil && issues.Err() != nil {
-		return "", issues.Err()
+		return nil, issues.Err()
 	}
 
 	prg, err := t.celEnv.Program(ast)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if t.lookups != nil {
+		tables := lookupTableNames(expr)
+		for _, fn := range t.functions {
+			if fn.calledIn(expr) {
+				tables = append(tables, fn.lookups...)
+			}
+		}
+
+		for _, name := range tables {
+			if err := t.lookups.Load(ctx, name); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Make sure that our input always has the 'cols' member
@@ -61,10 +243,10 @@ func (t *Env) Evaluate(ctx context.Context, expr string, inputs map[string]any)
 
 	out, _, err := prg.ContextEval(ctx, inputs)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return out.Value(), nil
+	return out, nil
 }
 
 func (t *Env) EvaluateString(ctx context.Context, expr string, inputs map[string]any) (string, error) {
@@ -162,6 +344,16 @@ func (t *Env) ProvisioningInputs(principal *v2.Resource, entitlement *v2.Entitle
 	return ret, nil
 }
 
+// WithGrantInputs adds the grant being revoked to provisioning inputs, with the data stored on it during sync.
+func (t *Env) WithGrantInputs(inputs map[string]any, grant *v2.Grant, data map[string]any) map[string]any {
+	inputs["grant"] = map[string]any{
+		"ID":   grant.GetId(),
+		"data": data,
+	}
+
+	return inputs
+}
+
 func (t *Env) AccountProvisioningInputs(inputs map[string]any) (map[string]any, error) {
 	ret := make(map[string]any)
 
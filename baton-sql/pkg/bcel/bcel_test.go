@@ -1,7 +1,9 @@
 package bcel
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -29,3 +31,191 @@ func TestTemplateEnv_Evaluate(tt *testing.T) {
 		}
 	}
 }
+
+func TestNewEnv_ExtensionLibraries(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := NewEnv(ctx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr     string
+		inputs   map[string]any
+		expected any
+	}{
+		{".email.lowerAscii()", map[string]any{"cols": map[string]any{"email": "Jane@Example.COM"}}, "jane@example.com"},
+		{"'a,b'.split(',').size()", map[string]any{}, int64(2)},
+		{".role.replace('_', ' ')", map[string]any{"cols": map[string]any{"role": "db_admin"}}, "db admin"},
+		{"[3, 1, 2].sort()[0]", map[string]any{}, int64(1)},
+		{"sets.contains(['admin', 'editor'], [.role])", map[string]any{"cols": map[string]any{"role": "admin"}}, true},
+		{"math.greatest(.a, .b)", map[string]any{"cols": map[string]any{"a": int64(3), "b": int64(9)}}, int64(9)},
+		{"base64.encode(bytes(.name))", map[string]any{"cols": map[string]any{"name": "jane"}}, "amFuZQ=="},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			out, err := env.Evaluate(ctx, tc.expr, tc.inputs)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out)
+		})
+	}
+}
+
+func TestEnv_EvaluateValue(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := NewEnv(ctx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr     string
+		inputs   map[string]any
+		expected any
+	}{
+		{".active", map[string]any{"cols": map[string]any{"active": true}}, true},
+		{".level + 1", map[string]any{"cols": map[string]any{"level": int64(2)}}, float64(3)},
+		{".name", map[string]any{"cols": map[string]any{"name": "jane"}}, "jane"},
+		{".groups.split(',')", map[string]any{"cols": map[string]any{"groups": "a,b"}}, []any{"a", "b"}},
+		{"{'admin': .role == 'admin', 'tags': []}", map[string]any{"cols": map[string]any{"role": "admin"}}, map[string]any{"admin": true, "tags": []any{}}},
+		{".manager", map[string]any{"cols": map[string]any{"manager": nil}}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			out, err := env.EvaluateValue(ctx, tc.expr, tc.inputs)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out.AsInterface())
+		})
+	}
+}
+
+func TestEnv_EvaluateExactValue(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := NewEnv(ctx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr     string
+		inputs   map[string]any
+		expected any
+		typeName string
+	}{
+		{".id", map[string]any{"cols": map[string]any{"id": int64(1<<62 + 1)}}, "4611686018427387905", "int"},
+		{"uint(.id)", map[string]any{"cols": map[string]any{"id": int64(7)}}, "7", "uint"},
+		{".score", map[string]any{"cols": map[string]any{"score": 2.0}}, 2.0, "double"},
+		{".name", map[string]any{"cols": map[string]any{"name": "jane"}}, "jane", "string"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			out, typeName, err := env.EvaluateExactValue(ctx, tc.expr, tc.inputs)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out.AsInterface())
+			require.Equal(t, tc.typeName, typeName)
+		})
+	}
+}
+
+func TestNewEnv_Functions(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := NewEnv(ctx, WithFunctions(map[string]string{
+		"normLogin(s)":        "toLower(trim(s))",
+		"roleSlug(role, env)": "slugify(role) + '-' + env",
+		"loginKey()":          "normLogin(.login)",
+		"same(x)":             "x",
+		"anyAdmin(roles)":     "roles.exists(r, normLogin(r) == 'admin')",
+		"department()":        ".DEPT",
+		"isOneOrTwo(v)":       "[1, 2].exists(x, x == v)",
+		"nested(l)":           "l.all(x, [x].exists(x, isOneOrTwo(x)))",
+	}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr     string
+		inputs   map[string]any
+		expected any
+	}{
+		{"normLogin(.login)", map[string]any{"cols": map[string]any{"login": "  JDoe "}}, "jdoe"},
+		{"roleSlug(.role, 'prod')", map[string]any{"cols": map[string]any{"role": "Site Admin"}}, "site-admin-prod"},
+		{"loginKey()", map[string]any{"cols": map[string]any{"login": "JDoe"}}, "jdoe"},
+		{"same(1) + same(2)", map[string]any{}, int64(3)},
+		{"anyAdmin(['Editor', ' ADMIN'])", map[string]any{}, true},
+		{"department()", map[string]any{"cols": map[string]any{"DEPT": "Eng"}}, "Eng"},
+		{"normLogin(normLogin(' S '))", map[string]any{}, "s"},
+		{"join([' A', 'B '].map(s, normLogin(s)), ',')", map[string]any{}, "a,b"},
+		{"[1, 3].all(x, isOneOrTwo(x))", map[string]any{}, false},
+		{"[1, 2].all(x, isOneOrTwo(x))", map[string]any{}, true},
+		{"[[1, 2]].all(x, nested(x))", map[string]any{}, true},
+		{"[[1, 3]].exists(x, nested(x))", map[string]any{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			out, err := env.Evaluate(ctx, tc.expr, tc.inputs)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out)
+		})
+	}
+}
+
+func TestNewEnv_FunctionErrors(t *testing.T) {
+	ctx := t.Context()
+
+	tests := []struct {
+		name string
+		defs map[string]string
+		err  string
+	}{
+		{"Direct recursion", map[string]string{"f(x)": "f(x) + 1"}, "function f is recursive: f -> f"},
+		{"Indirect recursion", map[string]string{"a(x)": "b(x)", "b(x)": "c(x)", "c(x)": "a(x)"}, "function a is recursive: a -> b -> c -> a"},
+		{"Invalid signature", map[string]string{"f(x": "x"}, "invalid function signature"},
+		{"Duplicate parameter", map[string]string{"f(x, x)": "x"}, "duplicate parameter name"},
+		{"Built-in name", map[string]string{"trim(s)": "s"}, "conflicts with a built-in function"},
+		{"Duplicate name", map[string]string{"f(x)": "x", "f(x, y)": "y"}, "defined more than once"},
+		{"Syntax error", map[string]string{"f(x)": "x +"}, "function f(x)"},
+		{"Shadowed parameter", map[string]string{"f(x)": "[1].exists(x, x > 0)"}, "parameter x is shadowed"},
+		{"Columns parameter", map[string]string{"f(cols)": ".name + cols"}, `parameter name "cols" in function signature "f(cols)" conflicts with a built-in variable`},
+		{"Variable parameter", map[string]string{"f(principal)": "principal"}, "conflicts with a built-in variable"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEnv(ctx, WithFunctions(tc.defs))
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+type testLookups struct {
+	loaded []string
+}
+
+func (l *testLookups) Load(ctx context.Context, name string) error {
+	l.loaded = append(l.loaded, name)
+	return nil
+}
+
+func (l *testLookups) Lookup(name string, key any) (map[string]any, error) {
+	if !slices.Contains(l.loaded, name) {
+		return nil, fmt.Errorf("lookup table %s is not loaded", name)
+	}
+	return map[string]any{"name": fmt.Sprintf("%s-%v", name, key)}, nil
+}
+
+func TestNewEnv_FunctionLookups(t *testing.T) {
+	ctx := t.Context()
+
+	lookups := &testLookups{}
+	env, err := NewEnv(ctx, WithLookups(lookups), WithFunctions(map[string]string{
+		"deptName(id)": "lookup('departments', id).name",
+		"label(id)":    "deptName(id) + '!'",
+	}))
+	require.NoError(t, err)
+
+	out, err := env.Evaluate(ctx, "label(.dept_id)", map[string]any{"cols": map[string]any{"dept_id": int64(7)}})
+	require.NoError(t, err)
+	require.Equal(t, "departments-7!", out)
+	require.Equal(t, []string{"departments"}, lookups.loaded)
+}
@@ -0,0 +1,76 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// maxCoalesceArgs is the most arguments coalesce accepts. CEL has no variadic functions, so an overload is
+// declared for each argument count.
+const maxCoalesceArgs = 6
+
+// Coalesce returns the first value that is not null, or null if every value is null.
+func Coalesce(values ...ref.Val) ref.Val {
+	for _, v := range values {
+		if v.Type() != types.NullType {
+			return v
+		}
+	}
+	return types.NullValue
+}
+
+func CoalesceFunc() *FunctionDefinition {
+	fd := &FunctionDefinition{
+		Name: "coalesce",
+	}
+
+	for argCount := 2; argCount <= maxCoalesceArgs; argCount++ {
+		args := make([]*types.Type, argCount)
+		for ii := range args {
+			args[ii] = types.DynType
+		}
+
+		overload := &OverloadDefinition{
+			Operator:   "coalesce" + strings.Repeat("_dyn", argCount),
+			Args:       args,
+			ResultType: types.DynType,
+			Function:   Coalesce,
+		}
+
+		if argCount == 2 {
+			overload.TestCases = []*ExprTestCase{
+				{
+					Expr:     `coalesce(.nickname, .first_name)`,
+					Expected: "Jane",
+					Inputs:   map[string]any{"cols": map[string]any{"nickname": nil, "first_name": "Jane"}},
+				},
+				{
+					Expr:     `coalesce(.nickname, .first_name)`,
+					Expected: "JJ",
+					Inputs:   map[string]any{"cols": map[string]any{"nickname": "JJ", "first_name": "Jane"}},
+				},
+			}
+		}
+
+		if argCount == 3 {
+			overload.TestCases = []*ExprTestCase{
+				{
+					Expr:     `coalesce(.work_email, .personal_email, 'unknown')`,
+					Expected: "unknown",
+					Inputs:   map[string]any{"cols": map[string]any{"work_email": nil, "personal_email": nil}},
+				},
+				{
+					Expr:     `coalesce(.manager_id, .owner_id, 0)`,
+					Expected: int64(42),
+					Inputs:   map[string]any{"cols": map[string]any{"manager_id": nil, "owner_id": int64(42)}},
+				},
+			}
+		}
+
+		fd.Overloads = append(fd.Overloads, overload)
+	}
+
+	return fd
+}
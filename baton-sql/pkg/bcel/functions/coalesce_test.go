@@ -0,0 +1,30 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Values   []ref.Val
+		Expected ref.Val
+	}{
+		{"first value", []ref.Val{types.String("a"), types.String("b")}, types.String("a")},
+		{"skips nulls", []ref.Val{types.NullValue, types.NullValue, types.Int(3)}, types.Int(3)},
+		{"empty string is not null", []ref.Val{types.String(""), types.String("b")}, types.String("")},
+		{"all null", []ref.Val{types.NullValue, types.NullValue}, types.NullValue},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := Coalesce(test.Values...)
+			if result.Equal(test.Expected) != types.True {
+				t.Errorf("Coalesce() = %v; want %v", result, test.Expected)
+			}
+		})
+	}
+}
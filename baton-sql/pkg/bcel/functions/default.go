@@ -0,0 +1,55 @@
+package functions
+
+import (
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// Default returns fallback if value is null or an empty string, and value otherwise.
+func Default(value ref.Val, fallback ref.Val) ref.Val {
+	if value.Type() == types.NullType {
+		return fallback
+	}
+
+	if s, ok := value.(types.String); ok && s == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func DefaultFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "default",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "default_dyn_dyn",
+				Args:       []*types.Type{types.DynType, types.DynType},
+				ResultType: types.DynType,
+				Binary:     Default,
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `default(.department, 'Unassigned')`,
+						Expected: "Unassigned",
+						Inputs:   map[string]any{"cols": map[string]any{"department": nil}},
+					},
+					{
+						Expr:     `default(.department, 'Unassigned')`,
+						Expected: "Unassigned",
+						Inputs:   map[string]any{"cols": map[string]any{"department": ""}},
+					},
+					{
+						Expr:     `default(.department, 'Unassigned')`,
+						Expected: "Engineering",
+						Inputs:   map[string]any{"cols": map[string]any{"department": "Engineering"}},
+					},
+					{
+						Expr:     `default(.login_count, 0)`,
+						Expected: int64(7),
+						Inputs:   map[string]any{"cols": map[string]any{"login_count": int64(7)}},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Value    ref.Val
+		Fallback ref.Val
+		Expected ref.Val
+	}{
+		{"null", types.NullValue, types.String("none"), types.String("none")},
+		{"empty string", types.String(""), types.String("none"), types.String("none")},
+		{"string", types.String("set"), types.String("none"), types.String("set")},
+		{"zero is kept", types.Int(0), types.Int(5), types.Int(0)},
+		{"false is kept", types.False, types.True, types.False},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := Default(test.Value, test.Fallback)
+			if result.Equal(test.Expected) != types.True {
+				t.Errorf("Default() = %v; want %v", result, test.Expected)
+			}
+		})
+	}
+}
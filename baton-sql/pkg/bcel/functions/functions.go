@@ -22,6 +22,7 @@ type OverloadDefinition struct {
 	ResultType *cel.Type
 	Unary      functions.UnaryOp
 	Binary     functions.BinaryOp
+	Function   functions.FunctionOp
 	TestCases  []*ExprTestCase
 }
 
@@ -55,6 +56,15 @@ func (fd *FunctionDefinition) GetOptions() []cel.EnvOption {
 					cel.BinaryBinding(overload.Binary),
 				),
 			)
+		case overload.Function != nil:
+			fn = cel.Function(fd.Name,
+				cel.Overload(
+					overload.Operator,
+					overload.Args,
+					overload.ResultType,
+					cel.FunctionBinding(overload.Function),
+				),
+			)
 		default:
 			panic("invalid overload definition")
 		}
@@ -69,9 +79,39 @@ func GetAllFunctions() []*FunctionDefinition {
 		ToUpperFunc(),
 		PHPDeserializeStringArrayFunc(),
 		PHPSerializeStringArrayFunc(),
+		PHPUnserializeFunc(),
+		PHPSerializeFunc(),
 		TitleCaseFunc(),
 		ToLowerFunc(),
 		SlugifyFunc(),
+		RegexExtractFunc(),
+		RegexReplaceFunc(),
+		SplitFunc(),
+		JoinFunc(),
+		TrimFunc(),
+		CoalesceFunc(),
+		DefaultFunc(),
+		ParseTimeFunc(),
+		FormatTimeFunc(),
+		UnixSecondsFunc(),
+		UnixMillisFunc(),
+		NowFunc(),
+		DaysFunc(),
+		DaysSinceFunc(),
+		ParseJSONFunc(),
+		JSONPathFunc(),
+		ToJSONFunc(),
+		BcryptFunc(),
+		Argon2idFunc(),
+		PBKDF2Func(),
+		PhpassPortableFunc(),
+		SHA512CryptFunc(),
+		SHA256HexFunc(),
+		MD5HexFunc(),
+		HexFunc(),
+		Base64Func(),
+		UUIDv5Func(),
+		CompositeKeyFunc(),
 	}
 }
 
@@ -0,0 +1,357 @@
+package functions
+
+import (
+	"crypto/md5" //nolint:gosec // md5hex derives IDs from existing MD5 based keys, it is not used for security.
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/uuid"
+)
+
+// maxCompositeKeyArgs is the most arguments compositeKey accepts. Longer keys can pass a list instead.
+const maxCompositeKeyArgs = 8
+
+// uuidNamespaces are the predefined namespaces of RFC 9562 that uuidv5 accepts by name.
+var uuidNamespaces = map[string]uuid.UUID{
+	"dns":  uuid.NameSpaceDNS,
+	"url":  uuid.NameSpaceURL,
+	"oid":  uuid.NameSpaceOID,
+	"x500": uuid.NameSpaceX500,
+}
+
+// compositeKeyEscaper percent-encodes the characters that have a meaning in composite keys. ':' is escaped too,
+// because it separates the parts of entitlement and grant IDs.
+var compositeKeyEscaper = strings.NewReplacer("%", "%25", "|", "%7C", ":", "%3A")
+
+// SHA256Hex returns the lower-case hex encoded SHA-256 digest of data.
+func SHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// MD5Hex returns the lower-case hex encoded MD5 digest of data.
+func MD5Hex(data []byte) string {
+	sum := md5.Sum(data) //nolint:gosec // md5hex derives IDs from existing MD5 based keys, it is not used for security.
+	return hex.EncodeToString(sum[:])
+}
+
+// UUIDv5 returns the name based UUID of name in namespace. The namespace is either a UUID or one of the predefined
+// namespaces dns, url, oid and x500.
+func UUIDv5(namespace string, name []byte) (string, error) {
+	ns, ok := uuidNamespaces[strings.ToLower(namespace)]
+	if !ok {
+		var err error
+		ns, err = uuid.Parse(namespace)
+		if err != nil {
+			return "", fmt.Errorf("invalid namespace %s, expected a UUID or one of dns, url, oid and x500", namespace)
+		}
+	}
+
+	return uuid.NewSHA1(ns, name).String(), nil
+}
+
+// CompositeKey joins values into a single key separated by '|'. '%', '|' and ':' are percent-encoded in each value
+// and a null value is written as %00, so distinct values always produce distinct keys.
+// Integers and strings with the same text produce the same key, so a key does not change if a driver returns
+// a numeric column as text. Bytes are hex encoded and timestamps are written in RFC 3339 format in UTC.
+func CompositeKey(values ...ref.Val) (string, error) {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		var part string
+		switch val := v.(type) {
+		case types.Null:
+			part = "%00"
+		case types.String:
+			part = compositeKeyEscaper.Replace(string(val))
+		case types.Int:
+			part = strconv.FormatInt(int64(val), 10)
+		case types.Uint:
+			part = strconv.FormatUint(uint64(val), 10)
+		case types.Double:
+			part = strconv.FormatFloat(float64(val), 'g', -1, 64)
+		case types.Bool:
+			part = strconv.FormatBool(bool(val))
+		case types.Bytes:
+			part = hex.EncodeToString(val)
+		case types.Timestamp:
+			part = compositeKeyEscaper.Replace(val.UTC().Format(time.RFC3339Nano))
+		default:
+			return "", fmt.Errorf("unsupported key value of type %s", v.Type().TypeName())
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, "|"), nil
+}
+
+// bytesArg returns the contents of a string or bytes argument.
+func bytesArg(fn string, v ref.Val) ([]byte, ref.Val) {
+	switch val := v.(type) {
+	case types.String:
+		return []byte(val), nil
+	case types.Bytes:
+		return val, nil
+	default:
+		return nil, types.NewErr("invalid argument to %s, expected string or bytes", fn)
+	}
+}
+
+// bytesFunc defines a function of a single string or bytes argument that returns a string.
+func bytesFunc(name string, fn func([]byte) string, stringTests []*ExprTestCase, bytesTests []*ExprTestCase) *FunctionDefinition {
+	unary := func(v ref.Val) ref.Val {
+		data, errVal := bytesArg(name, v)
+		if errVal != nil {
+			return errVal
+		}
+		return types.String(fn(data))
+	}
+
+	return &FunctionDefinition{
+		Name: name,
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   name + "_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.StringType,
+				Unary:      unary,
+				TestCases:  stringTests,
+			},
+			{
+				Operator:   name + "_bytes",
+				Args:       []*types.Type{types.BytesType},
+				ResultType: types.StringType,
+				Unary:      unary,
+				TestCases:  bytesTests,
+			},
+		},
+	}
+}
+
+func SHA256HexFunc() *FunctionDefinition {
+	return bytesFunc("sha256hex", SHA256Hex,
+		[]*ExprTestCase{
+			{
+				Expr:     `sha256hex('abc')`,
+				Expected: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			},
+			{
+				Expr:     `sha256hex(compositeKey(.tenant, .login))`,
+				Expected: SHA256Hex([]byte("acme|jdoe")),
+				Inputs:   map[string]any{"cols": map[string]any{"tenant": "acme", "login": "jdoe"}},
+			},
+		},
+		[]*ExprTestCase{
+			{
+				Expr:     `sha256hex(b'abc')`,
+				Expected: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			},
+		},
+	)
+}
+
+func MD5HexFunc() *FunctionDefinition {
+	return bytesFunc("md5hex", MD5Hex,
+		[]*ExprTestCase{
+			{
+				Expr:     `md5hex('abc')`,
+				Expected: "900150983cd24fb0d6963f7d28e17f72",
+			},
+		},
+		[]*ExprTestCase{
+			{
+				Expr:     `md5hex(.id)`,
+				Expected: "900150983cd24fb0d6963f7d28e17f72",
+				Inputs:   map[string]any{"cols": map[string]any{"id": []byte("abc")}},
+			},
+		},
+	)
+}
+
+func HexFunc() *FunctionDefinition {
+	return bytesFunc("hex", hex.EncodeToString,
+		[]*ExprTestCase{
+			{
+				Expr:     `hex('abc')`,
+				Expected: "616263",
+			},
+		},
+		[]*ExprTestCase{
+			{
+				Expr:     `hex(.object_guid)`,
+				Expected: "00ff10",
+				Inputs:   map[string]any{"cols": map[string]any{"object_guid": []byte{0x00, 0xff, 0x10}}},
+			},
+		},
+	)
+}
+
+// Base64Func encodes with the unpadded URL-safe alphabet, so IDs contain no "/", "+" or "=". The standard encoding
+// is available from base64.encode.
+func Base64Func() *FunctionDefinition {
+	return bytesFunc("base64", base64.RawURLEncoding.EncodeToString,
+		[]*ExprTestCase{
+			{
+				Expr:     `base64('abc?')`,
+				Expected: "YWJjPw",
+			},
+		},
+		[]*ExprTestCase{
+			{
+				Expr:     `base64(.sid)`,
+				Expected: "AP8Q-w",
+				Inputs:   map[string]any{"cols": map[string]any{"sid": []byte{0x00, 0xff, 0x10, 0xfb}}},
+			},
+			{
+				Expr:     `base64.encode(b'abc?')`,
+				Expected: "YWJjPw==",
+			},
+		},
+	)
+}
+
+func UUIDv5Func() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "uuidv5",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "uuidv5_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					return uuidv5(lhs, rhs)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `uuidv5('dns', 'www.example.com')`,
+						Expected: "2ed6657d-e927-568b-95e1-2665a8aea6a2",
+					},
+					{
+						Expr:     `uuidv5('6ba7b810-9dad-11d1-80b4-00c04fd430c8', .host)`,
+						Expected: "2ed6657d-e927-568b-95e1-2665a8aea6a2",
+						Inputs:   map[string]any{"cols": map[string]any{"host": "www.example.com"}},
+					},
+				},
+			},
+			{
+				Operator:   "uuidv5_string_bytes",
+				Args:       []*types.Type{types.StringType, types.BytesType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					return uuidv5(lhs, rhs)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `uuidv5('dns', b'www.example.com')`,
+						Expected: "2ed6657d-e927-568b-95e1-2665a8aea6a2",
+					},
+				},
+			},
+		},
+	}
+}
+
+func uuidv5(lhs ref.Val, rhs ref.Val) ref.Val {
+	ns, ok := lhs.(types.String)
+	if !ok {
+		return types.NewErr("invalid namespace for uuidv5, expected string")
+	}
+	name, errVal := bytesArg("uuidv5", rhs)
+	if errVal != nil {
+		return errVal
+	}
+
+	result, err := UUIDv5(string(ns), name)
+	if err != nil {
+		return types.NewErr("uuidv5: %w", err)
+	}
+	return types.String(result)
+}
+
+func CompositeKeyFunc() *FunctionDefinition {
+	compositeKey := func(values ...ref.Val) ref.Val {
+		result, err := CompositeKey(values...)
+		if err != nil {
+			return types.NewErr("compositeKey: %w", err)
+		}
+		return types.String(result)
+	}
+
+	fd := &FunctionDefinition{
+		Name: "compositeKey",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "compositeKey_list",
+				Args:       []*types.Type{types.NewListType(types.DynType)},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					list, ok := v.(traits.Lister)
+					if !ok {
+						return types.NewErr("invalid argument to compositeKey, expected list")
+					}
+					var values []ref.Val
+					it := list.Iterator()
+					for it.HasNext() == types.True {
+						values = append(values, it.Next())
+					}
+					return compositeKey(values...)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `compositeKey(['a', 'b', 'c'])`,
+						Expected: "a|b|c",
+					},
+				},
+			},
+		},
+	}
+
+	for argCount := 2; argCount <= maxCompositeKeyArgs; argCount++ {
+		args := make([]*types.Type, argCount)
+		for ii := range args {
+			args[ii] = types.DynType
+		}
+
+		overload := &OverloadDefinition{
+			Operator:   "compositeKey" + strings.Repeat("_dyn", argCount),
+			Args:       args,
+			ResultType: types.StringType,
+			Function:   compositeKey,
+		}
+
+		if argCount == 2 {
+			overload.TestCases = []*ExprTestCase{
+				{
+					Expr:     `compositeKey(.tenant_id, .login)`,
+					Expected: "42|jdoe",
+					Inputs:   map[string]any{"cols": map[string]any{"tenant_id": int64(42), "login": "jdoe"}},
+				},
+				{
+					Expr:     `compositeKey('a|b', 'c') != compositeKey('a', 'b|c')`,
+					Expected: true,
+				},
+			}
+		}
+
+		if argCount == 3 {
+			overload.TestCases = []*ExprTestCase{
+				{
+					Expr:     `compositeKey(.schema, .table, .grantee)`,
+					Expected: "public|orders|%00",
+					Inputs:   map[string]any{"cols": map[string]any{"schema": "public", "table": "orders", "grantee": nil}},
+				},
+			}
+		}
+
+		fd.Overloads = append(fd.Overloads, overload)
+	}
+
+	return fd
+}
@@ -0,0 +1,73 @@
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func TestUUIDv5(t *testing.T) {
+	tests := []struct {
+		Namespace string
+		Name      string
+		Expected  string
+	}{
+		{"dns", "www.example.com", "2ed6657d-e927-568b-95e1-2665a8aea6a2"},
+		{"DNS", "www.example.com", "2ed6657d-e927-568b-95e1-2665a8aea6a2"},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "www.example.com", "2ed6657d-e927-568b-95e1-2665a8aea6a2"},
+		{"url", "http://www.example.com/", "fcde3c85-2270-590f-9e7c-ee003d65e0e2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Namespace+"/"+test.Name, func(t *testing.T) {
+			result, err := UUIDv5(test.Namespace, []byte(test.Name))
+			if err != nil {
+				t.Fatalf("UUIDv5(%q, %q) returned error: %v", test.Namespace, test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("UUIDv5(%q, %q) = %q; want %q", test.Namespace, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := UUIDv5("not-a-namespace", []byte("x")); err == nil {
+		t.Errorf("UUIDv5 with an invalid namespace should return an error")
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Values   []ref.Val
+		Expected string
+	}{
+		{"strings", []ref.Val{types.String("a"), types.String("b")}, "a|b"},
+		{"separator", []ref.Val{types.String("a|b"), types.String("c")}, "a%7Cb|c"},
+		{"colon", []ref.Val{types.String("db:1"), types.String("x")}, "db%3A1|x"},
+		{"percent", []ref.Val{types.String("100%7C"), types.String("x")}, "100%257C|x"},
+		{"null and empty", []ref.Val{types.NullValue, types.String("")}, "%00|"},
+		{"empty and null", []ref.Val{types.String(""), types.NullValue}, "|%00"},
+		{"literal null marker", []ref.Val{types.String("%00")}, "%2500"},
+		{"numbers", []ref.Val{types.Int(-4), types.Uint(7), types.Double(1.5), types.Bool(true)}, "-4|7|1.5|true"},
+		{"bytes", []ref.Val{types.Bytes{0xde, 0xad}}, "dead"},
+		{"timestamp", []ref.Val{types.Timestamp{Time: time.Date(2025, 4, 17, 14, 30, 45, 0, time.FixedZone("CEST", 2*60*60))}}, "2025-04-17T12%3A30%3A45Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := CompositeKey(test.Values...)
+			if err != nil {
+				t.Fatalf("CompositeKey() returned error: %v", err)
+			}
+			if result != test.Expected {
+				t.Errorf("CompositeKey() = %q; want %q", result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := CompositeKey(types.NewStringList(types.DefaultTypeAdapter, []string{"a"})); err == nil {
+		t.Errorf("CompositeKey with a list value should return an error")
+	}
+}
@@ -0,0 +1,77 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// Join concatenates the string form of each item, separated by sep. Null items are skipped.
+func Join(items []ref.Val, sep string) (string, error) {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Type() == types.NullType {
+			continue
+		}
+
+		s, ok := item.ConvertToType(types.StringType).(types.String)
+		if !ok {
+			return "", fmt.Errorf("cannot convert %s to string", item.Type().TypeName())
+		}
+		parts = append(parts, string(s))
+	}
+
+	return strings.Join(parts, sep), nil
+}
+
+func JoinFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "join",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "join_list_string",
+				Args:       []*types.Type{types.NewListType(types.DynType), types.StringType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					list, ok := lhs.(traits.Lister)
+					if !ok {
+						return types.NewErr("invalid argument to join, expected list")
+					}
+					sep, ok := rhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid separator for join, expected string")
+					}
+
+					var items []ref.Val
+					it := list.Iterator()
+					for it.HasNext() == types.True {
+						items = append(items, it.Next())
+					}
+
+					result, err := Join(items, string(sep))
+					if err != nil {
+						return types.NewErr("invalid argument to join: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `join(['admin', 'editor'], ', ')`,
+						Expected: "admin, editor",
+					},
+					{
+						Expr:     `join([1, 'a', null, true], '-')`,
+						Expected: "1-a-true",
+					},
+					{
+						Expr:     `join([], ',')`,
+						Expected: "",
+					},
+				},
+			},
+		},
+	}
+}
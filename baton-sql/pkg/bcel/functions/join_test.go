@@ -0,0 +1,38 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Items    []ref.Val
+		Sep      string
+		Expected string
+	}{
+		{"strings", []ref.Val{types.String("admin"), types.String("editor")}, ",", "admin,editor"},
+		{"mixed types", []ref.Val{types.Int(1), types.Double(2.5), types.Bool(true)}, " ", "1 2.5 true"},
+		{"nulls are skipped", []ref.Val{types.String("a"), types.NullValue, types.String("b")}, "|", "a|b"},
+		{"empty", nil, ",", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := Join(test.Items, test.Sep)
+			if err != nil {
+				t.Fatalf("Join() returned error: %v", err)
+			}
+			if result != test.Expected {
+				t.Errorf("Join() = %q; want %q", result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := Join([]ref.Val{types.NewDynamicList(types.DefaultTypeAdapter, []string{"a"})}, ","); err == nil {
+		t.Errorf("Join() with a nested list should return an error")
+	}
+}
@@ -0,0 +1,427 @@
+package functions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// ParseJSON decodes a JSON document. Integral numbers are returned as int64 and other numbers as float64,
+// so ids stored in JSON compare equal to integer columns.
+func ParseJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var ret any
+	if err := dec.Decode(&ret); err != nil {
+		return nil, err
+	}
+
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	return convertJSONNumbers(ret), nil
+}
+
+func convertJSONNumbers(val any) any {
+	switch v := val.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, item := range v {
+			v[k] = convertJSONNumbers(item)
+		}
+		return v
+	case []any:
+		for ii, item := range v {
+			v[ii] = convertJSONNumbers(item)
+		}
+		return v
+	default:
+		return val
+	}
+}
+
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the supported JSONPath subset: $, .name, ['name'], [n], [-n], .* and [*].
+// The leading $ may be omitted, as in `roles[0]`.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if rest, ok := strings.CutPrefix(path, "$"); ok {
+		path = rest
+	} else if path != "" && path[0] != '.' && path[0] != '[' {
+		path = "." + path
+	}
+
+	var steps []jsonPathStep
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			if strings.HasPrefix(path, "..") {
+				return nil, errors.New("recursive descent is not supported")
+			}
+			path = path[1:]
+
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			name := path[:end]
+			if name == "" {
+				return nil, errors.New("empty field name")
+			}
+			path = path[end:]
+
+			if name == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: name})
+			}
+
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, errors.New("unterminated [")
+			}
+			sel := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+
+			switch {
+			case sel == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0]:
+				steps = append(steps, jsonPathStep{key: sel[1 : len(sel)-1]})
+			default:
+				idx, err := strconv.Atoi(sel)
+				if err != nil {
+					return nil, fmt.Errorf("invalid selector [%s]", sel)
+				}
+				steps = append(steps, jsonPathStep{index: idx, isIndex: true})
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", path[0])
+		}
+	}
+
+	return steps, nil
+}
+
+// JSONPath evaluates a JSONPath expression against a decoded JSON document. Paths without wildcards return the
+// selected value, or nil if it does not exist. Paths with wildcards return a list of every selected value,
+// with object members in key order.
+func JSONPath(doc any, path string) (any, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %s: %w", path, err)
+	}
+
+	current := []any{doc}
+	definite := true
+	for _, step := range steps {
+		var next []any
+		for _, node := range current {
+			switch v := node.(type) {
+			case map[string]any:
+				switch {
+				case step.wildcard:
+					for _, k := range slices.Sorted(maps.Keys(v)) {
+						next = append(next, v[k])
+					}
+				case !step.isIndex:
+					if item, ok := v[step.key]; ok {
+						next = append(next, item)
+					}
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, v...)
+				case step.isIndex:
+					idx := step.index
+					if idx < 0 {
+						idx += len(v)
+					}
+					if idx >= 0 && idx < len(v) {
+						next = append(next, v[idx])
+					}
+				}
+			}
+		}
+
+		if step.wildcard {
+			definite = false
+		}
+		current = next
+	}
+
+	if !definite {
+		if current == nil {
+			current = []any{}
+		}
+		return current, nil
+	}
+
+	if len(current) == 0 {
+		return nil, nil
+	}
+	return current[0], nil
+}
+
+// ToJSON encodes a CEL value as JSON. Timestamps are encoded as RFC 3339 strings, durations as Go duration strings
+// and bytes as base64. Map keys are sorted.
+func ToJSON(val ref.Val) (string, error) {
+	native, err := jsonNative(val)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := json.Marshal(native)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// jsonNative converts a CEL value into a value encoding/json can marshal.
+func jsonNative(val ref.Val) (any, error) {
+	switch v := val.(type) {
+	case types.Null:
+		return nil, nil
+	case types.Bool:
+		return bool(v), nil
+	case types.Int:
+		return int64(v), nil
+	case types.Uint:
+		return uint64(v), nil
+	case types.Double:
+		return float64(v), nil
+	case types.String:
+		return string(v), nil
+	case types.Bytes:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case types.Timestamp:
+		return v.Format(time.RFC3339Nano), nil
+	case types.Duration:
+		return v.String(), nil
+	case traits.Mapper:
+		ret := make(map[string]any)
+		it := v.Iterator()
+		for it.HasNext() == types.True {
+			k := it.Next()
+			key, ok := k.(types.String)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, got %s", k.Type().TypeName())
+			}
+			item, err := jsonNative(v.Get(k))
+			if err != nil {
+				return nil, err
+			}
+			ret[string(key)] = item
+		}
+		return ret, nil
+	case traits.Lister:
+		ret := make([]any, 0)
+		it := v.Iterator()
+		for it.HasNext() == types.True {
+			item, err := jsonNative(it.Next())
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, item)
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("cannot encode %s as JSON", val.Type().TypeName())
+	}
+}
+
+// jsonDocument returns the decoded document for a jsonPath argument, which is either JSON text or an
+// already decoded value such as the result of parseJSON.
+func jsonDocument(val ref.Val) (any, error) {
+	switch v := val.(type) {
+	case types.String:
+		return ParseJSON([]byte(v))
+	case types.Bytes:
+		return ParseJSON(v)
+	default:
+		return jsonNative(val)
+	}
+}
+
+func ParseJSONFunc() *FunctionDefinition {
+	parse := func(data []byte) ref.Val {
+		ret, err := ParseJSON(data)
+		if err != nil {
+			return types.NewErr("parseJSON: %w", err)
+		}
+		return types.DefaultTypeAdapter.NativeToValue(ret)
+	}
+
+	return &FunctionDefinition{
+		Name: "parseJSON",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "parseJSON_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.DynType,
+				Unary: func(v ref.Val) ref.Val {
+					s, ok := v.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to parseJSON, expected string")
+					}
+					return parse([]byte(s))
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `parseJSON('{"roles": ["admin", "editor"]}').roles[1]`,
+						Expected: "editor",
+					},
+					{
+						Expr:     `'admin' in parseJSON(.attrs).roles`,
+						Expected: true,
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{"roles": ["admin"], "level": 3}`}},
+					},
+					{
+						Expr:     `parseJSON(.attrs).level == 3`,
+						Expected: true,
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{"roles": ["admin"], "level": 3}`}},
+					},
+				},
+			},
+			{
+				Operator:   "parseJSON_bytes",
+				Args:       []*types.Type{types.BytesType},
+				ResultType: types.DynType,
+				Unary: func(v ref.Val) ref.Val {
+					b, ok := v.(types.Bytes)
+					if !ok {
+						return types.NewErr("invalid argument to parseJSON, expected bytes")
+					}
+					return parse(b)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `parseJSON(.attrs).department`,
+						Expected: "Engineering",
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": []byte(`{"department": "Engineering"}`)}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func JSONPathFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "jsonPath",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "jsonPath_dyn_string",
+				Args:       []*types.Type{types.DynType, types.StringType},
+				ResultType: types.DynType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					path, ok := rhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid path for jsonPath, expected string")
+					}
+
+					doc, err := jsonDocument(lhs)
+					if err != nil {
+						return types.NewErr("jsonPath: %w", err)
+					}
+
+					ret, err := JSONPath(doc, string(path))
+					if err != nil {
+						return types.NewErr("jsonPath: %w", err)
+					}
+					return types.DefaultTypeAdapter.NativeToValue(ret)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `jsonPath(.attrs, '$.roles[*]')`,
+						Expected: []any{"admin", "editor"},
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{"roles": ["admin", "editor"]}`}},
+					},
+					{
+						Expr:     `jsonPath(.attrs, '$.manager.email')`,
+						Expected: "boss@example.com",
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{"manager": {"email": "boss@example.com"}}`}},
+					},
+					{
+						Expr:     `jsonPath(.attrs, '$.groups[*].name').size()`,
+						Expected: int64(2),
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{"groups": [{"name": "a"}, {"name": "b"}]}`}},
+					},
+					{
+						Expr:     `jsonPath(parseJSON(.attrs), '$.roles[-1]')`,
+						Expected: "editor",
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{"roles": ["admin", "editor"]}`}},
+					},
+					{
+						Expr:     `jsonPath(.attrs, '$.missing') == null`,
+						Expected: true,
+						Inputs:   map[string]any{"cols": map[string]any{"attrs": `{}`}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func ToJSONFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "toJSON",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "toJSON_dyn",
+				Args:       []*types.Type{types.DynType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					ret, err := ToJSON(v)
+					if err != nil {
+						return types.NewErr("toJSON: %w", err)
+					}
+					return types.String(ret)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `toJSON({'roles': ['admin'], 'active': true, 'level': 3})`,
+						Expected: `{"active":true,"level":3,"roles":["admin"]}`,
+					},
+					{
+						Expr:     `toJSON(split(.roles, ','))`,
+						Expected: `["admin","editor"]`,
+						Inputs:   map[string]any{"cols": map[string]any{"roles": "admin,editor"}},
+					},
+					{
+						Expr:     `toJSON(null)`,
+						Expected: `null`,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,110 @@
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected any
+	}{
+		{`{"id": 42, "ratio": 0.5}`, map[string]any{"id": int64(42), "ratio": 0.5}},
+		{`["a", null, true]`, []any{"a", nil, true}},
+		{`"text"`, "text"},
+		{`12345678901234567`, int64(12345678901234567)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			result, err := ParseJSON([]byte(test.Input))
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, result)
+		})
+	}
+
+	for _, input := range []string{``, `{"a": }`, `{} {}`} {
+		_, err := ParseJSON([]byte(input))
+		require.Error(t, err, "ParseJSON(%q) should return an error", input)
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	doc := map[string]any{
+		"roles": []any{"admin", "editor"},
+		"manager": map[string]any{
+			"email": "boss@example.com",
+		},
+		"groups": []any{
+			map[string]any{"name": "a", "id": int64(1)},
+			map[string]any{"name": "b", "id": int64(2)},
+		},
+		"odd key": "value",
+	}
+
+	tests := []struct {
+		Path     string
+		Expected any
+	}{
+		{"$", doc},
+		{"$.roles", []any{"admin", "editor"}},
+		{"$.roles[0]", "admin"},
+		{"$.roles[-1]", "editor"},
+		{"$.roles[5]", nil},
+		{"$.roles[*]", []any{"admin", "editor"}},
+		{"$.manager.email", "boss@example.com"},
+		{"$['manager']['email']", "boss@example.com"},
+		{`$["odd key"]`, "value"},
+		{"$.groups[*].name", []any{"a", "b"}},
+		{"$.groups[1].id", int64(2)},
+		{"$.manager.*", []any{"boss@example.com"}},
+		{"$.missing", nil},
+		{"$.missing[*]", []any{}},
+		{"roles[0]", "admin"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			result, err := JSONPath(doc, test.Path)
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, result)
+		})
+	}
+
+	for _, path := range []string{"$..roles", "$.roles[abc]", "$.roles[0", "$.", "$ roles"} {
+		_, err := JSONPath(doc, path)
+		require.Error(t, err, "JSONPath(%q) should return an error", path)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    ref.Val
+		Expected string
+	}{
+		{"null", types.NullValue, `null`},
+		{"string", types.String("a\"b"), `"a\"b"`},
+		{"int", types.Int(9007199254740993), `9007199254740993`},
+		{"list", types.DefaultTypeAdapter.NativeToValue([]any{"a", int64(1), nil}), `["a",1,null]`},
+		{"map", types.DefaultTypeAdapter.NativeToValue(map[string]any{"b": true, "a": 1.5}), `{"a":1.5,"b":true}`},
+		{"timestamp", types.Timestamp{Time: time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)}, `"2025-04-17T14:30:45Z"`},
+		{"bytes", types.Bytes("hi"), `"aGk="`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ToJSON(test.Input)
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, result)
+		})
+	}
+
+	_, err := ToJSON(types.DefaultTypeAdapter.NativeToValue(map[int64]any{1: "a"}))
+	require.Error(t, err)
+}
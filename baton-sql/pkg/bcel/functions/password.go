@@ -0,0 +1,646 @@
+package functions
+
+import (
+	"crypto/md5" //nolint:gosec // phpass portable hashes are defined in terms of MD5.
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // PBKDF2-SHA1 is still required by some applications.
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cryptAlphabet is the base64 alphabet used by crypt(3) style hashes, including phpass.
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ab64Encoding is passlib's adapted base64: standard base64 without padding, with . in place of +.
+var ab64Encoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").WithPadding(base64.NoPadding)
+
+// Upper bounds of the cost parameters, so one expression cannot tie up unbounded memory or CPU for every row.
+const (
+	argon2MaxMemory      = 1024 * 1024 // 1 GiB, in KiB
+	argon2MaxIterations  = 64
+	argon2MaxLength      = 1024
+	pbkdf2MaxIterations  = 10_000_000
+	sha512CryptMaxRounds = 10_000_000
+)
+
+const (
+	phpassDefaultLog2     = 13
+	sha512CryptRounds     = 5000
+	sha512CryptMinRounds  = 1000
+	sha512CryptMaxSaltLen = 16
+)
+
+// Argon2Params are the cost parameters of an argon2id hash. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	KeyLength   uint32
+	SaltLength  uint32
+}
+
+// DefaultArgon2Params follows the first recommended option of RFC 9106 for memory constrained environments.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	KeyLength:   32,
+	SaltLength:  16,
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// randomCryptSalt returns n random characters from the crypt(3) alphabet.
+func randomCryptSalt(n int) (string, error) {
+	var sb strings.Builder
+	alphabetLen := big.NewInt(int64(len(cryptAlphabet)))
+	for range n {
+		idx, err := rand.Int(rand.Reader, alphabetLen)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(cryptAlphabet[idx.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// Bcrypt hashes password with bcrypt at the given cost, e.g. $2a$10$....
+func Bcrypt(password string, cost int) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Argon2id hashes password with argon2id and a random salt, and returns it in the PHC string format used by
+// PHP's password_hash and most libraries, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.
+// Memory is limited to 1 GiB, iterations to 64 and the key and salt lengths to 1024 bytes.
+func Argon2id(password string, params Argon2Params) (string, error) {
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 || params.KeyLength == 0 || params.SaltLength == 0 {
+		return "", fmt.Errorf("argon2id parameters must be greater than zero")
+	}
+	if params.Memory > argon2MaxMemory {
+		return "", fmt.Errorf("argon2id memory must be at most %d KiB", argon2MaxMemory)
+	}
+	if params.Iterations > argon2MaxIterations {
+		return "", fmt.Errorf("argon2id iterations must be at most %d", argon2MaxIterations)
+	}
+	if params.KeyLength > argon2MaxLength || params.SaltLength > argon2MaxLength {
+		return "", fmt.Errorf("argon2id key and salt lengths must be at most %d bytes", argon2MaxLength)
+	}
+
+	salt, err := randomBytes(int(params.SaltLength))
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// PBKDF2 derives a key from password with the given digest (sha1, sha256 or sha512) and returns it in the format of
+// passlib's pbkdf2_sha* hashes, e.g. $pbkdf2-sha256$29000$<salt>$<hash>. Like passlib, the salt and hash are encoded with
+// its adapted base64, which uses . instead of +. A random 16-byte salt is used if salt is empty. Iterations are limited
+// to 10,000,000.
+func PBKDF2(password string, digest string, iterations int, salt []byte) (string, error) {
+	var h func() hash.Hash
+	var keyLen int
+	switch digest {
+	case "sha1":
+		h, keyLen = sha1.New, sha1.Size
+	case "sha256":
+		h, keyLen = sha256.New, sha256.Size
+	case "sha512":
+		h, keyLen = sha512.New, sha512.Size
+	default:
+		return "", fmt.Errorf("unsupported pbkdf2 digest %s, expected sha1, sha256 or sha512", digest)
+	}
+
+	if iterations < 1 || iterations > pbkdf2MaxIterations {
+		return "", fmt.Errorf("pbkdf2 iterations must be between 1 and %d", pbkdf2MaxIterations)
+	}
+
+	var err error
+	if len(salt) == 0 {
+		salt, err = randomBytes(16)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	key, err := pbkdf2.Key(h, password, salt, iterations, keyLen)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("$pbkdf2-%s$%d$%s$%s",
+		digest,
+		iterations,
+		ab64Encoding.EncodeToString(salt),
+		ab64Encoding.EncodeToString(key),
+	), nil
+}
+
+// PhpassPortable hashes password with the portable phpass scheme used by WordPress before 6.8, e.g. $P$B....
+// salt must be 8 characters from the crypt alphabet and log2Iterations between 7 and 30.
+func PhpassPortable(password string, salt string, log2Iterations int) (string, error) {
+	if len(salt) != 8 {
+		return "", fmt.Errorf("phpass salt must be 8 characters")
+	}
+	if log2Iterations < 7 || log2Iterations > 30 {
+		return "", fmt.Errorf("phpass iteration count must be between 2^7 and 2^30")
+	}
+
+	sum := md5.Sum([]byte(salt + password)) //nolint:gosec // phpass portable hashes are defined in terms of MD5.
+	checksum := sum[:]
+	for range 1 << log2Iterations {
+		sum = md5.Sum(append(checksum, password...)) //nolint:gosec // phpass portable hashes are defined in terms of MD5.
+		checksum = sum[:]
+	}
+
+	return "$P$" + string(cryptAlphabet[log2Iterations]) + salt + phpassEncode(checksum), nil
+}
+
+// phpassEncode is phpass's encode64, which packs bytes little-endian into 6-bit groups.
+func phpassEncode(input []byte) string {
+	var sb strings.Builder
+	for ii := 0; ii < len(input); ii += 3 {
+		value := int(input[ii])
+		sb.WriteByte(cryptAlphabet[value&0x3f])
+		if ii+1 < len(input) {
+			value |= int(input[ii+1]) << 8
+		}
+		sb.WriteByte(cryptAlphabet[(value>>6)&0x3f])
+		if ii+1 >= len(input) {
+			break
+		}
+		if ii+2 < len(input) {
+			value |= int(input[ii+2]) << 16
+		}
+		sb.WriteByte(cryptAlphabet[(value>>12)&0x3f])
+		if ii+2 >= len(input) {
+			break
+		}
+		sb.WriteByte(cryptAlphabet[(value>>18)&0x3f])
+	}
+	return sb.String()
+}
+
+// sha512CryptOrder is the order in which SHA-crypt encodes the 64 digest bytes, three at a time.
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+// SHA512Crypt hashes password with the SHA-512 based crypt(3) scheme used by glibc, e.g. $6$<salt>$....
+// The salt is truncated to 16 characters and rounds below 1000 are raised to 1000, as the specification requires.
+// Rounds above 10,000,000 are rejected. The rounds are only included in the output when they differ from the default
+// of 5000.
+func SHA512Crypt(password string, salt string, rounds int) (string, error) {
+	if rounds > sha512CryptMaxRounds {
+		return "", fmt.Errorf("sha512Crypt rounds must be at most %d", sha512CryptMaxRounds)
+	}
+
+	if len(salt) > sha512CryptMaxSaltLen {
+		salt = salt[:sha512CryptMaxSaltLen]
+	}
+
+	customRounds := rounds != sha512CryptRounds
+	rounds = max(rounds, sha512CryptMinRounds)
+
+	pw := []byte(password)
+	s := []byte(salt)
+
+	b := sha512.New()
+	b.Write(pw)
+	b.Write(s)
+	b.Write(pw)
+	altSum := b.Sum(nil)
+
+	a := sha512.New()
+	a.Write(pw)
+	a.Write(s)
+	ii := len(pw)
+	for ; ii > sha512.Size; ii -= sha512.Size {
+		a.Write(altSum)
+	}
+	a.Write(altSum[:ii])
+	for ii = len(pw); ii > 0; ii >>= 1 {
+		if ii&1 != 0 {
+			a.Write(altSum)
+		} else {
+			a.Write(pw)
+		}
+	}
+	sum := a.Sum(nil)
+
+	dp := sha512.New()
+	for range len(pw) {
+		dp.Write(pw)
+	}
+	p := repeatBytes(dp.Sum(nil), len(pw))
+
+	ds := sha512.New()
+	for range 16 + int(sum[0]) {
+		ds.Write(s)
+	}
+	sp := repeatBytes(ds.Sum(nil), len(s))
+
+	for r := range rounds {
+		c := sha512.New()
+		if r&1 != 0 {
+			c.Write(p)
+		} else {
+			c.Write(sum)
+		}
+		if r%3 != 0 {
+			c.Write(sp)
+		}
+		if r%7 != 0 {
+			c.Write(p)
+		}
+		if r&1 != 0 {
+			c.Write(sum)
+		} else {
+			c.Write(p)
+		}
+		sum = c.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("$6$")
+	if customRounds {
+		sb.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	sb.WriteString(salt)
+	sb.WriteByte('$')
+	for _, idx := range sha512CryptOrder {
+		writeCrypt24(&sb, sum[idx[0]], sum[idx[1]], sum[idx[2]], 4)
+	}
+	writeCrypt24(&sb, 0, 0, sum[63], 2)
+
+	return sb.String(), nil
+}
+
+func repeatBytes(src []byte, n int) []byte {
+	ret := make([]byte, 0, n)
+	for len(ret) < n {
+		ret = append(ret, src[:min(len(src), n-len(ret))]...)
+	}
+	return ret
+}
+
+func writeCrypt24(sb *strings.Builder, b2 byte, b1 byte, b0 byte, n int) {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for range n {
+		sb.WriteByte(cryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+}
+
+// argon2Params reads argon2id parameters from a CEL map, starting from the defaults.
+func argon2Params(v ref.Val) (Argon2Params, error) {
+	params := DefaultArgon2Params
+
+	m, ok := v.(traits.Mapper)
+	if !ok {
+		return params, fmt.Errorf("expected a map of parameters")
+	}
+
+	it := m.Iterator()
+	for it.HasNext() == types.True {
+		k := it.Next()
+		key, ok := k.(types.String)
+		if !ok {
+			return params, fmt.Errorf("parameter names must be strings")
+		}
+		val, ok := m.Get(k).(types.Int)
+		if !ok || val < 1 || val > types.Int(^uint32(0)) {
+			return params, fmt.Errorf("parameter %s must be a positive integer", key)
+		}
+
+		switch string(key) {
+		case "memory":
+			params.Memory = uint32(val)
+		case "iterations":
+			params.Iterations = uint32(val)
+		case "parallelism":
+			if val > 255 {
+				return params, fmt.Errorf("parameter parallelism must be at most 255")
+			}
+			params.Parallelism = uint8(val)
+		case "key_length":
+			params.KeyLength = uint32(val)
+		case "salt_length":
+			params.SaltLength = uint32(val)
+		default:
+			return params, fmt.Errorf("unknown parameter %s, expected one of %s", key,
+				strings.Join([]string{"memory", "iterations", "parallelism", "key_length", "salt_length"}, ", "))
+		}
+	}
+
+	return params, nil
+}
+
+func BcryptFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "bcrypt",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "bcrypt_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					pw, ok := v.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to bcrypt, expected string")
+					}
+					result, err := Bcrypt(string(pw), bcrypt.DefaultCost)
+					if err != nil {
+						return types.NewErr("bcrypt: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `bcrypt('secret').matches('^\\$2a\\$10\\$[./A-Za-z0-9]{53}$')`,
+						Expected: true,
+					},
+				},
+			},
+			{
+				Operator:   "bcrypt_string_int",
+				Args:       []*types.Type{types.StringType, types.IntType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					pw, ok := lhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to bcrypt, expected string")
+					}
+					cost, ok := rhs.(types.Int)
+					if !ok || cost < types.Int(bcrypt.MinCost) || cost > types.Int(bcrypt.MaxCost) {
+						return types.NewErr("invalid cost for bcrypt, expected an integer between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+					}
+					result, err := Bcrypt(string(pw), int(cost))
+					if err != nil {
+						return types.NewErr("bcrypt: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `bcrypt(.password, 4).startsWith('$2a$04$')`,
+						Expected: true,
+						Inputs:   map[string]any{"cols": map[string]any{"password": "hunter2"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func Argon2idFunc() *FunctionDefinition {
+	hashWith := func(pw ref.Val, params Argon2Params) ref.Val {
+		s, ok := pw.(types.String)
+		if !ok {
+			return types.NewErr("invalid argument to argon2id, expected string")
+		}
+		result, err := Argon2id(string(s), params)
+		if err != nil {
+			return types.NewErr("argon2id: %w", err)
+		}
+		return types.String(result)
+	}
+
+	return &FunctionDefinition{
+		Name: "argon2id",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "argon2id_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					return hashWith(v, DefaultArgon2Params)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `argon2id('secret').startsWith('$argon2id$v=19$m=65536,t=3,p=4$')`,
+						Expected: true,
+					},
+				},
+			},
+			{
+				Operator:   "argon2id_string_map",
+				Args:       []*types.Type{types.StringType, types.NewMapType(types.StringType, types.IntType)},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					params, err := argon2Params(rhs)
+					if err != nil {
+						return types.NewErr("invalid parameters for argon2id: %w", err)
+					}
+					return hashWith(lhs, params)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `argon2id('secret', {'memory': 19456, 'iterations': 2, 'parallelism': 1}).startsWith('$argon2id$v=19$m=19456,t=2,p=1$')`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func PBKDF2Func() *FunctionDefinition {
+	hashWith := func(args []ref.Val) ref.Val {
+		pw, ok := args[0].(types.String)
+		if !ok {
+			return types.NewErr("invalid argument to pbkdf2, expected string")
+		}
+		digest, ok := args[1].(types.String)
+		if !ok {
+			return types.NewErr("invalid digest for pbkdf2, expected string")
+		}
+		iterations, ok := args[2].(types.Int)
+		if !ok || iterations > types.Int(^uint32(0)) {
+			return types.NewErr("invalid iterations for pbkdf2, expected int")
+		}
+
+		var salt []byte
+		if len(args) > 3 {
+			s, ok := args[3].(types.String)
+			if !ok || s == "" {
+				return types.NewErr("invalid salt for pbkdf2, expected non-empty string")
+			}
+			salt = []byte(s)
+		}
+
+		result, err := PBKDF2(string(pw), string(digest), int(iterations), salt)
+		if err != nil {
+			return types.NewErr("pbkdf2: %w", err)
+		}
+		return types.String(result)
+	}
+
+	return &FunctionDefinition{
+		Name: "pbkdf2",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "pbkdf2_string_string_int",
+				Args:       []*types.Type{types.StringType, types.StringType, types.IntType},
+				ResultType: types.StringType,
+				Function: func(args ...ref.Val) ref.Val {
+					if len(args) != 3 {
+						return types.NewErr("pbkdf2 expects 3 or 4 arguments")
+					}
+					return hashWith(args)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `pbkdf2('secret', 'sha256', 1000).startsWith('$pbkdf2-sha256$1000$')`,
+						Expected: true,
+					},
+				},
+			},
+			{
+				Operator:   "pbkdf2_string_string_int_string",
+				Args:       []*types.Type{types.StringType, types.StringType, types.IntType, types.StringType},
+				ResultType: types.StringType,
+				Function: func(args ...ref.Val) ref.Val {
+					if len(args) != 4 {
+						return types.NewErr("pbkdf2 expects 3 or 4 arguments")
+					}
+					return hashWith(args)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `pbkdf2('password', 'sha1', 1, 'salt')`,
+						Expected: "$pbkdf2-sha1$1$c2FsdA$DGDID5YfDnHzqbUkr2ASBi/gN6Y",
+					},
+				},
+			},
+		},
+	}
+}
+
+func PhpassPortableFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "phpassPortable",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "phpassPortable_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					pw, ok := v.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to phpassPortable, expected string")
+					}
+					salt, err := randomCryptSalt(8)
+					if err != nil {
+						return types.NewErr("phpassPortable: %w", err)
+					}
+					result, err := PhpassPortable(string(pw), salt, phpassDefaultLog2)
+					if err != nil {
+						return types.NewErr("phpassPortable: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `phpassPortable('secret').matches('^\\$P\\$B[./A-Za-z0-9]{30}$')`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func SHA512CryptFunc() *FunctionDefinition {
+	hashWith := func(pw ref.Val, rounds int) ref.Val {
+		s, ok := pw.(types.String)
+		if !ok {
+			return types.NewErr("invalid argument to sha512Crypt, expected string")
+		}
+		salt, err := randomCryptSalt(sha512CryptMaxSaltLen)
+		if err != nil {
+			return types.NewErr("sha512Crypt: %w", err)
+		}
+		result, err := SHA512Crypt(string(s), salt, rounds)
+		if err != nil {
+			return types.NewErr("sha512Crypt: %w", err)
+		}
+		return types.String(result)
+	}
+
+	return &FunctionDefinition{
+		Name: "sha512Crypt",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "sha512Crypt_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					return hashWith(v, sha512CryptRounds)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `sha512Crypt('secret').matches('^\\$6\\$[./A-Za-z0-9]{16}\\$[./A-Za-z0-9]{86}$')`,
+						Expected: true,
+					},
+				},
+			},
+			{
+				Operator:   "sha512Crypt_string_int",
+				Args:       []*types.Type{types.StringType, types.IntType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					rounds, ok := rhs.(types.Int)
+					if !ok || rounds < 1 {
+						return types.NewErr("invalid rounds for sha512Crypt, expected a positive integer")
+					}
+					if rounds > sha512CryptMaxRounds {
+						return types.NewErr("invalid rounds for sha512Crypt, expected at most %d", sha512CryptMaxRounds)
+					}
+					return hashWith(lhs, int(rounds))
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `sha512Crypt('secret', 10000).startsWith('$6$rounds=10000$')`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
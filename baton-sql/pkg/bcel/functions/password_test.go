@@ -0,0 +1,147 @@
+package functions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcrypt(t *testing.T) {
+	hashed, err := Bcrypt("hunter2", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Bcrypt returned error: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte("hunter2")); err != nil {
+		t.Errorf("Bcrypt(%q) = %q does not verify: %v", "hunter2", hashed, err)
+	}
+
+	if _, err := Bcrypt("hunter2", bcrypt.MaxCost+1); err == nil {
+		t.Errorf("Bcrypt with an invalid cost should return an error")
+	}
+}
+
+func TestArgon2id(t *testing.T) {
+	params := Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, KeyLength: 16, SaltLength: 8}
+	hashed, err := Argon2id("hunter2", params)
+	if err != nil {
+		t.Fatalf("Argon2id returned error: %v", err)
+	}
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Argon2id returned %q; want the PHC string format", hashed)
+	}
+
+	other, err := Argon2id("hunter2", params)
+	if err != nil {
+		t.Fatalf("Argon2id returned error: %v", err)
+	}
+	if hashed == other {
+		t.Errorf("Argon2id should use a random salt")
+	}
+
+	if _, err := Argon2id("hunter2", Argon2Params{}); err == nil {
+		t.Errorf("Argon2id with zero parameters should return an error")
+	}
+	if _, err := Argon2id("hunter2", Argon2Params{Memory: 4 * 1024 * 1024, Iterations: 1, Parallelism: 1, KeyLength: 16, SaltLength: 8}); err == nil {
+		t.Errorf("Argon2id with 4 GiB of memory should return an error")
+	}
+	if _, err := Argon2id("hunter2", Argon2Params{Memory: 1024, Iterations: 1000, Parallelism: 1, KeyLength: 16, SaltLength: 8}); err == nil {
+		t.Errorf("Argon2id with 1000 iterations should return an error")
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		Digest     string
+		Iterations int
+		Expected   string
+	}{
+		// RFC 6070 test vectors, in passlib's adapted base64.
+		{"sha1", 1, "$pbkdf2-sha1$1$c2FsdA$DGDID5YfDnHzqbUkr2ASBi/gN6Y"},
+		{"sha1", 4096, "$pbkdf2-sha1$4096$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Expected, func(t *testing.T) {
+			result, err := PBKDF2("password", test.Digest, test.Iterations, []byte("salt"))
+			if err != nil {
+				t.Fatalf("PBKDF2 returned error: %v", err)
+			}
+			if result != test.Expected {
+				t.Errorf("PBKDF2(%q, %d) = %q; want %q", test.Digest, test.Iterations, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := PBKDF2("password", "md5", 1, nil); err == nil {
+		t.Errorf("PBKDF2 with an unsupported digest should return an error")
+	}
+	if _, err := PBKDF2("password", "sha256", 0, nil); err == nil {
+		t.Errorf("PBKDF2 with zero iterations should return an error")
+	}
+	if _, err := PBKDF2("password", "sha256", 1_000_000_000, nil); err == nil {
+		t.Errorf("PBKDF2 with a billion iterations should return an error")
+	}
+}
+
+func TestPhpassPortable(t *testing.T) {
+	// Computed with a direct port of phpass's crypt_private.
+	result, err := PhpassPortable("test12345", "tuh4nOe2", 11)
+	if err != nil {
+		t.Fatalf("PhpassPortable returned error: %v", err)
+	}
+	if result != "$P$9tuh4nOe2xk8KAvA/UDXaesMYb7YqM1" {
+		t.Errorf("PhpassPortable = %q; want %q", result, "$P$9tuh4nOe2xk8KAvA/UDXaesMYb7YqM1")
+	}
+
+	if _, err := PhpassPortable("test12345", "short", 13); err == nil {
+		t.Errorf("PhpassPortable with a short salt should return an error")
+	}
+}
+
+func TestSHA512Crypt(t *testing.T) {
+	tests := []struct {
+		Password string
+		Salt     string
+		Rounds   int
+		Expected string
+	}{
+		// Test vectors from the SHA-crypt specification.
+		{
+			"Hello world!", "saltstring", 5000,
+			"$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+		},
+		{
+			"Hello world!", "saltstringsaltstring", 10000,
+			"$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+		},
+		{
+			"a very much longer text to encrypt.  This one even stretches over morethan one line.", "anotherlongsaltstring", 1400,
+			"$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1",
+		},
+		{
+			"we have a short salt string but not a short password", "short", 77777,
+			"$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0",
+		},
+		{
+			"the minimum number is still observed", "roundstoolow", 10,
+			"$6$rounds=1000$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Salt, func(t *testing.T) {
+			result, err := SHA512Crypt(test.Password, test.Salt, test.Rounds)
+			if err != nil {
+				t.Fatalf("SHA512Crypt returned error: %v", err)
+			}
+			if result != test.Expected {
+				t.Errorf("SHA512Crypt(%q, %q, %d) = %q; want %q", test.Password, test.Salt, test.Rounds, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := SHA512Crypt("password", "salt", 999_999_999); err == nil {
+		t.Errorf("SHA512Crypt with 999,999,999 rounds should return an error")
+	}
+}
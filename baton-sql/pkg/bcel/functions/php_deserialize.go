@@ -1,7 +1,10 @@
 package functions
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/elliotchance/phpserialize"
 	"github.com/google/cel-go/common/types"
@@ -69,3 +72,285 @@ func PHPDeserializeStringArrayFunc() *FunctionDefinition {
 		},
 	}
 }
+
+// phpMaxDepth bounds how deeply nested arrays and objects may be in phpUnserialize input.
+const phpMaxDepth = 512
+
+// PHPUnserialize decodes any value produced by PHP's serialize(). Arrays whose keys are 0..n-1 in order are returned
+// as []any and other arrays as map[any]any with int64 and string keys. Objects are returned as a map of their
+// properties. References and custom serialized objects are not supported.
+//
+// phpserialize.Unmarshal, used by PHPDeserializeStringArray, is not used here because it panics on truncated input,
+// rewrites backslash sequences inside strings that PHP never escapes, and ignores data after the value. Column
+// values are not trusted, so malformed input must return an error rather than stop the sync.
+func PHPUnserialize(s string) (any, error) {
+	d := &phpDecoder{data: s}
+	ret, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("unexpected data after value at offset %d", d.pos)
+	}
+	return ret, nil
+}
+
+type phpDecoder struct {
+	data string
+	pos  int
+}
+
+func (d *phpDecoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", d.pos, fmt.Sprintf(format, args...))
+}
+
+func (d *phpDecoder) expect(c byte) error {
+	if d.pos >= len(d.data) || d.data[d.pos] != c {
+		return d.errorf("expected %q", c)
+	}
+	d.pos++
+	return nil
+}
+
+// until returns the text up to the next c and moves past c.
+func (d *phpDecoder) until(c byte) (string, error) {
+	end := strings.IndexByte(d.data[d.pos:], c)
+	if end == -1 {
+		return "", d.errorf("expected %q", c)
+	}
+	ret := d.data[d.pos : d.pos+end]
+	d.pos += end + 1
+	return ret, nil
+}
+
+func (d *phpDecoder) length() (int, error) {
+	raw, err := d.until(':')
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, d.errorf("invalid length %q", raw)
+	}
+	return n, nil
+}
+
+// str reads the body of a string, <len>:"<bytes>", leaving the position after the closing quote.
+func (d *phpDecoder) str() (string, error) {
+	n, err := d.length()
+	if err != nil {
+		return "", err
+	}
+	if err := d.expect('"'); err != nil {
+		return "", err
+	}
+	if n > len(d.data)-d.pos {
+		return "", d.errorf("string length %d exceeds input", n)
+	}
+	ret := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return ret, d.expect('"')
+}
+
+func (d *phpDecoder) value(depth int) (any, error) {
+	if d.pos+1 >= len(d.data) {
+		return nil, d.errorf("unexpected end of input")
+	}
+
+	kind := d.data[d.pos]
+	if kind == 'N' {
+		d.pos++
+		return nil, d.expect(';')
+	}
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case 'b':
+		raw, err := d.until(';')
+		if err != nil {
+			return nil, err
+		}
+		switch raw {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+		return nil, d.errorf("invalid boolean %q", raw)
+
+	case 'i':
+		raw, err := d.until(';')
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, d.errorf("invalid integer %q", raw)
+		}
+		return i, nil
+
+	case 'd':
+		raw, err := d.until(';')
+		if err != nil {
+			return nil, err
+		}
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, d.errorf("invalid float %q", raw)
+		}
+		return f, nil
+
+	case 's':
+		s, err := d.str()
+		if err != nil {
+			return nil, err
+		}
+		return s, d.expect(';')
+
+	case 'a':
+		return d.array(depth + 1)
+
+	case 'O':
+		if _, err := d.str(); err != nil {
+			return nil, err
+		}
+		if err := d.expect(':'); err != nil {
+			return nil, err
+		}
+		props, err := d.array(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return phpObjectProperties(props), nil
+
+	case 'r', 'R':
+		return nil, d.errorf("references are not supported")
+
+	default:
+		return nil, d.errorf("unsupported type %q", kind)
+	}
+}
+
+// array reads the body of an array, <n>:{<key><value>...}.
+func (d *phpDecoder) array(depth int) (any, error) {
+	if depth > phpMaxDepth {
+		return nil, d.errorf("exceeded maximum depth of %d", phpMaxDepth)
+	}
+
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect('{'); err != nil {
+		return nil, err
+	}
+
+	keys := make([]any, 0, min(n, len(d.data)))
+	values := make([]any, 0, min(n, len(d.data)))
+	isList := true
+	for ii := range n {
+		key, err := d.value(depth)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case int64:
+			if k != int64(ii) {
+				isList = false
+			}
+		case string:
+			isList = false
+		default:
+			return nil, d.errorf("array keys must be integers or strings")
+		}
+
+		val, err := d.value(depth)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, val)
+	}
+
+	if err := d.expect('}'); err != nil {
+		return nil, err
+	}
+
+	if isList {
+		return values, nil
+	}
+
+	ret := make(map[any]any, len(keys))
+	for ii, k := range keys {
+		ret[k] = values[ii]
+	}
+	return ret, nil
+}
+
+// phpObjectProperties strips the visibility prefixes PHP adds to protected ("\0*\0name") and private
+// ("\0Class\0name") property names.
+func phpObjectProperties(props any) map[any]any {
+	ret := make(map[any]any)
+	switch v := props.(type) {
+	case []any:
+		for ii, item := range v {
+			ret[int64(ii)] = item
+		}
+	case map[any]any:
+		for k, item := range v {
+			if name, ok := k.(string); ok && strings.HasPrefix(name, "\x00") {
+				if idx := strings.IndexByte(name[1:], 0); idx != -1 {
+					k = name[idx+2:]
+				}
+			}
+			ret[k] = item
+		}
+	}
+	return ret
+}
+
+func PHPUnserializeFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "phpUnserialize",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "phpUnserialize_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.DynType,
+				Unary: func(v ref.Val) ref.Val {
+					s, ok := v.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to phpUnserialize, expected string")
+					}
+					result, err := PHPUnserialize(string(s))
+					if err != nil {
+						return types.NewErr("error while unserializing PHP string: %w", err)
+					}
+					return types.DefaultTypeAdapter.NativeToValue(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `phpUnserialize('a:1:{s:13:"administrator";b:1;}').administrator`,
+						Expected: true,
+					},
+					{
+						Expr:     `'editor' in phpUnserialize(.caps)`,
+						Expected: true,
+						Inputs:   map[string]any{"cols": map[string]any{"caps": `a:2:{s:6:"author";b:1;s:6:"editor";b:1;}`}},
+					},
+					{
+						Expr:     `phpUnserialize('a:2:{i:0;s:1:"a";i:1;a:1:{s:5:"level";i:3;}}')[1].level`,
+						Expected: int64(3),
+					},
+					{
+						Expr:     `phpUnserialize('a:3:{i:0;d:1.5;i:1;N;i:2;b:0;}')`,
+						Expected: []any{1.5, nil, false},
+					},
+				},
+			},
+		},
+	}
+}
@@ -57,3 +57,100 @@ func TestPHPDeserializeArray(t *testing.T) {
 		})
 	}
 }
+
+func TestPHPUnserialize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    any
+		wantErr bool
+	}{
+		{
+			name:  "capabilities",
+			input: `a:2:{s:13:"administrator";b:1;s:6:"editor";b:0;}`,
+			want:  map[any]any{"administrator": true, "editor": false},
+		},
+		{
+			name:  "list",
+			input: `a:3:{i:0;s:1:"a";i:1;i:-7;i:2;d:0.5;}`,
+			want:  []any{"a", int64(-7), 0.5},
+		},
+		{
+			name:  "sparse_keys",
+			input: `a:2:{i:1;s:1:"a";i:5;N;}`,
+			want:  map[any]any{int64(1): "a", int64(5): nil},
+		},
+		{
+			name:  "nested",
+			input: `a:1:{s:5:"roles";a:1:{i:0;a:1:{s:4:"name";s:6:"editor";}}}`,
+			want:  map[any]any{"roles": []any{map[any]any{"name": "editor"}}},
+		},
+		{
+			name:  "multibyte_and_quotes",
+			input: `s:9:"café "x"";`,
+			want:  `café "x"`,
+		},
+		{
+			name:  "exponent",
+			input: `d:1.0E+25;`,
+			want:  1e25,
+		},
+		{
+			name:  "object",
+			input: "O:4:\"User\":2:{s:4:\"name\";s:3:\"bob\";s:8:\"\x00User\x00id\";i:7;}",
+			want:  map[any]any{"name": "bob", "id": int64(7)},
+		},
+		{
+			name:  "empty",
+			input: `a:0:{}`,
+			want:  []any{},
+		},
+		{
+			name:    "truncated_string",
+			input:   `s:10:"short";`,
+			wantErr: true,
+		},
+		{
+			name:    "truncated_array_string",
+			input:   `a:1:{s:10:"ab";b:1;}`,
+			wantErr: true,
+		},
+		{
+			name:    "truncated_bool",
+			input:   `a:1:{s:1:"a";b`,
+			wantErr: true,
+		},
+		{
+			name:  "backslashes",
+			input: `s:4:"a\nb";`,
+			want:  `a\nb`,
+		},
+		{
+			name:    "trailing_data",
+			input:   `i:1;i:2;`,
+			wantErr: true,
+		},
+		{
+			name:    "reference",
+			input:   `a:2:{i:0;i:1;i:1;R:2;}`,
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			input:   `not serialized`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PHPUnserialize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PHPUnserialize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PHPUnserialize() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,17 @@
 package functions
 
 import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/elliotchance/phpserialize"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
 )
 
 // PHPSerializeStringArray serializes a slice of strings into a PHP serialized string.
@@ -62,3 +70,209 @@ func PHPSerializeStringArrayFunc() *FunctionDefinition {
 		},
 	}
 }
+
+// PHPSerialize encodes a CEL value the way PHP's serialize() encodes the equivalent PHP value. Lists become arrays
+// with keys 0..n-1 and map keys are written in PHP array key form: integer keys, and string keys that are canonical
+// integers, become integer keys. Map entries are written with integer keys first in ascending order, then string keys
+// in ascending order. Because of this, phpSerialize(phpUnserialize(x)) only returns x byte for byte when the keys of
+// every array in x were already in that order.
+func PHPSerialize(val ref.Val) (string, error) {
+	var sb strings.Builder
+	if err := phpSerializeValue(&sb, val); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func phpSerializeValue(sb *strings.Builder, val ref.Val) error {
+	switch v := val.(type) {
+	case types.Null:
+		sb.WriteString("N;")
+	case types.Bool:
+		if v {
+			sb.WriteString("b:1;")
+		} else {
+			sb.WriteString("b:0;")
+		}
+	case types.Int:
+		sb.WriteString("i:" + strconv.FormatInt(int64(v), 10) + ";")
+	case types.Uint:
+		if v > math.MaxInt64 {
+			return fmt.Errorf("%d overflows a PHP integer", uint64(v))
+		}
+		sb.WriteString("i:" + strconv.FormatUint(uint64(v), 10) + ";")
+	case types.Double:
+		sb.WriteString("d:" + phpFloat(float64(v)) + ";")
+	case types.String:
+		phpSerializeString(sb, string(v))
+	case types.Bytes:
+		phpSerializeString(sb, string(v))
+	case traits.Mapper:
+		return phpSerializeMap(sb, v)
+	case traits.Lister:
+		size, ok := v.Size().(types.Int)
+		if !ok {
+			return fmt.Errorf("invalid list size")
+		}
+		sb.WriteString("a:" + strconv.FormatInt(int64(size), 10) + ":{")
+		for ii := types.Int(0); ii < size; ii++ {
+			sb.WriteString("i:" + strconv.FormatInt(int64(ii), 10) + ";")
+			if err := phpSerializeValue(sb, v.Get(ii)); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("}")
+	default:
+		return fmt.Errorf("cannot serialize %s", val.Type().TypeName())
+	}
+	return nil
+}
+
+func phpSerializeString(sb *strings.Builder, s string) {
+	sb.WriteString("s:" + strconv.Itoa(len(s)) + ":\"" + s + "\";")
+}
+
+type phpArrayEntry struct {
+	intKey   int64
+	strKey   string
+	isString bool
+	value    ref.Val
+}
+
+func phpSerializeMap(sb *strings.Builder, m traits.Mapper) error {
+	var entries []phpArrayEntry
+	it := m.Iterator()
+	for it.HasNext() == types.True {
+		k := it.Next()
+		entry := phpArrayEntry{value: m.Get(k)}
+		switch key := k.(type) {
+		case types.Int:
+			entry.intKey = int64(key)
+		case types.Uint:
+			if key > math.MaxInt64 {
+				return fmt.Errorf("map key %d overflows a PHP integer", uint64(key))
+			}
+			entry.intKey = int64(key)
+		case types.Bool:
+			if key {
+				entry.intKey = 1
+			}
+		case types.String:
+			if i, ok := phpIntegerKey(string(key)); ok {
+				entry.intKey = i
+			} else {
+				entry.strKey = string(key)
+				entry.isString = true
+			}
+		default:
+			return fmt.Errorf("cannot use %s as a PHP array key", k.Type().TypeName())
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b phpArrayEntry) int {
+		switch {
+		case a.isString != b.isString:
+			if a.isString {
+				return 1
+			}
+			return -1
+		case a.isString:
+			return strings.Compare(a.strKey, b.strKey)
+		default:
+			return cmp.Compare(a.intKey, b.intKey)
+		}
+	})
+
+	sb.WriteString("a:" + strconv.Itoa(len(entries)) + ":{")
+	for _, entry := range entries {
+		if entry.isString {
+			phpSerializeString(sb, entry.strKey)
+		} else {
+			sb.WriteString("i:" + strconv.FormatInt(entry.intKey, 10) + ";")
+		}
+		if err := phpSerializeValue(sb, entry.value); err != nil {
+			return err
+		}
+	}
+	sb.WriteString("}")
+	return nil
+}
+
+// phpIntegerKey reports whether PHP would store the string s as an integer array key, e.g. "5" but not "05" or "5.0".
+func phpIntegerKey(s string) (int64, bool) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(i, 10) != s || s == "-0" {
+		return 0, false
+	}
+	return i, true
+}
+
+// phpFloat formats f like PHP with serialize_precision -1: the shortest representation that round trips, in
+// exponent form (e.g. 1.0E+25) only for very large or small magnitudes.
+func phpFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NAN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	}
+
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(e, "e")
+	decpt, _ := strconv.Atoi(exp)
+	decpt++
+
+	if f == 0 || (decpt >= -3 && decpt <= 17) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	exp = strconv.Itoa(decpt - 1)
+	if decpt > 0 {
+		exp = "+" + exp
+	}
+	return mantissa + "E" + exp
+}
+
+func PHPSerializeFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "phpSerialize",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "phpSerialize_dyn",
+				Args:       []*types.Type{types.DynType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					result, err := PHPSerialize(v)
+					if err != nil {
+						return types.NewErr("error while serializing PHP value: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `phpSerialize({'editor': true, 'author': true})`,
+						Expected: `a:2:{s:6:"author";b:1;s:6:"editor";b:1;}`,
+					},
+					{
+						Expr:     `phpSerialize(['a', 1, 2.5, null])`,
+						Expected: `a:4:{i:0;s:1:"a";i:1;i:1;i:2;d:2.5;i:3;N;}`,
+					},
+					{
+						Expr:     `phpSerialize({'5': 'five', 'key': 1e25})`,
+						Expected: `a:2:{i:5;s:4:"five";s:3:"key";d:1.0E+25;}`,
+					},
+					{
+						Expr:     `phpSerialize(phpUnserialize('a:2:{i:0;s:1:"x";s:4:"meta";a:1:{s:5:"level";i:3;}}'))`,
+						Expected: `a:2:{i:0;s:1:"x";s:4:"meta";a:1:{s:5:"level";i:3;}}`,
+					},
+				},
+			},
+		},
+	}
+}
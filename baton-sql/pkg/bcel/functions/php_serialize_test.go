@@ -2,6 +2,8 @@ package functions
 
 import (
 	"testing"
+
+	"github.com/google/cel-go/common/types"
 )
 
 func TestPHPSerializeArray(t *testing.T) {
@@ -50,3 +52,66 @@ func TestPHPSerializeArray(t *testing.T) {
 		})
 	}
 }
+
+func TestPHPSerializeRoundTrip(t *testing.T) {
+	tests := []string{
+		`a:1:{s:13:"administrator";b:1;}`,
+		`a:3:{i:0;s:1:"a";i:1;i:-7;i:2;d:0.5;}`,
+		`a:2:{i:1;s:1:"a";i:5;N;}`,
+		`a:2:{i:0;s:1:"x";s:4:"meta";a:1:{s:5:"level";i:3;}}`,
+		`s:9:"café "x"";`,
+		`d:1.0E+25;`,
+		`d:1.0E-5;`,
+		`d:0.0001;`,
+		`b:0;`,
+		`a:0:{}`,
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			val, err := PHPUnserialize(tt)
+			if err != nil {
+				t.Fatalf("PHPUnserialize() error = %v", err)
+			}
+			got, err := PHPSerialize(types.DefaultTypeAdapter.NativeToValue(val))
+			if err != nil {
+				t.Fatalf("PHPSerialize() error = %v", err)
+			}
+			if got != tt {
+				t.Errorf("PHPSerialize() got = %v, want %v", got, tt)
+			}
+		})
+	}
+}
+
+func TestPHPSerializeRoundTrip_SortsKeys(t *testing.T) {
+	val, err := PHPUnserialize(`a:3:{s:1:"b";i:1;i:5;i:2;s:1:"a";i:3;}`)
+	if err != nil {
+		t.Fatalf("PHPUnserialize() error = %v", err)
+	}
+	got, err := PHPSerialize(types.DefaultTypeAdapter.NativeToValue(val))
+	if err != nil {
+		t.Fatalf("PHPSerialize() error = %v", err)
+	}
+	want := `a:3:{i:5;i:2;s:1:"a";i:3;s:1:"b";i:1;}`
+	if got != want {
+		t.Errorf("PHPSerialize() got = %v, want %v", got, want)
+	}
+}
+
+func TestPHPFloat(t *testing.T) {
+	tests := map[float64]string{
+		0:       "0",
+		1:       "1",
+		-2.5:    "-2.5",
+		0.1:     "0.1",
+		1e16:    "10000000000000000",
+		1e18:    "1.0E+18",
+		1.5e-7:  "1.5E-7",
+		1234.25: "1234.25",
+	}
+	for input, want := range tests {
+		if got := phpFloat(input); got != want {
+			t.Errorf("phpFloat(%v) = %v, want %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package functions
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// regexCache holds compiled patterns, since the same pattern is evaluated once per row.
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+// RegexExtract returns the first capture group of the first match of pattern in s, or the whole match if the pattern
+// has no capture groups. It returns an empty string if there is no match.
+func RegexExtract(s string, pattern string) (string, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	match := re.FindStringSubmatch(s)
+	switch {
+	case match == nil:
+		return "", nil
+	case len(match) > 1:
+		return match[1], nil
+	default:
+		return match[0], nil
+	}
+}
+
+// RegexReplace replaces every match of pattern in s. The replacement can reference capture groups as $1 or ${name}.
+func RegexReplace(s string, pattern string, replacement string) (string, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+func RegexExtractFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "regexExtract",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "regexExtract_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					s, ok := lhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to regexExtract, expected string")
+					}
+					pattern, ok := rhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid pattern for regexExtract, expected string")
+					}
+
+					result, err := RegexExtract(string(s), string(pattern))
+					if err != nil {
+						return types.NewErr("invalid pattern for regexExtract: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `regexExtract('CN=Admins,OU=Groups,DC=example,DC=com', 'CN=([^,]+)')`,
+						Expected: "Admins",
+					},
+					{
+						Expr:     `regexExtract('order 12345 shipped', '[0-9]+')`,
+						Expected: "12345",
+					},
+					{
+						Expr:     `regexExtract('no digits', '[0-9]+')`,
+						Expected: "",
+					},
+					{
+						Expr:     `regexExtract(.email, '@(.+)$')`,
+						Expected: "example.com",
+						Inputs:   map[string]any{"cols": map[string]any{"email": "jane@example.com"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func RegexReplaceFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "regexReplace",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "regexReplace_string_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType, types.StringType},
+				ResultType: types.StringType,
+				Function: func(args ...ref.Val) ref.Val {
+					if len(args) != 3 {
+						return types.NewErr("regexReplace expects 3 arguments")
+					}
+
+					var strs [3]string
+					for ii, arg := range args {
+						s, ok := arg.(types.String)
+						if !ok {
+							return types.NewErr("invalid argument to regexReplace, expected string")
+						}
+						strs[ii] = string(s)
+					}
+
+					result, err := RegexReplace(strs[0], strs[1], strs[2])
+					if err != nil {
+						return types.NewErr("invalid pattern for regexReplace: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `regexReplace('555-123-4567', '[^0-9]', '')`,
+						Expected: "5551234567",
+					},
+					{
+						Expr:     `regexReplace('Doe, Jane', '^(\\w+), (\\w+)$', '$2 $1')`,
+						Expected: "Jane Doe",
+					},
+					{
+						Expr:     `regexReplace('unchanged', 'x+', 'y')`,
+						Expected: "unchanged",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package functions
+
+import "testing"
+
+func TestRegexExtract(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Pattern  string
+		Expected string
+	}{
+		{"CN=Admins,OU=Groups", "CN=([^,]+)", "Admins"},
+		{"order 12345 shipped", "[0-9]+", "12345"},
+		{"no digits", "[0-9]+", ""},
+		{"key=value", "(?P<key>\\w+)=(\\w+)", "key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			result, err := RegexExtract(test.Input, test.Pattern)
+			if err != nil {
+				t.Fatalf("RegexExtract(%q, %q) returned error: %v", test.Input, test.Pattern, err)
+			}
+			if result != test.Expected {
+				t.Errorf("RegexExtract(%q, %q) = %q; want %q", test.Input, test.Pattern, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := RegexExtract("abc", "("); err == nil {
+		t.Errorf("RegexExtract with an invalid pattern should return an error")
+	}
+}
+
+func TestRegexReplace(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Pattern     string
+		Replacement string
+		Expected    string
+	}{
+		{"555-123-4567", "[^0-9]", "", "5551234567"},
+		{"Doe, Jane", "^(\\w+), (\\w+)$", "$2 $1", "Jane Doe"},
+		{"a  b   c", "\\s+", " ", "a b c"},
+		{"unchanged", "x+", "y", "unchanged"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			result, err := RegexReplace(test.Input, test.Pattern, test.Replacement)
+			if err != nil {
+				t.Fatalf("RegexReplace(%q, %q, %q) returned error: %v", test.Input, test.Pattern, test.Replacement, err)
+			}
+			if result != test.Expected {
+				t.Errorf("RegexReplace(%q, %q, %q) = %q; want %q", test.Input, test.Pattern, test.Replacement, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := RegexReplace("abc", "[", ""); err == nil {
+		t.Errorf("RegexReplace with an invalid pattern should return an error")
+	}
+}
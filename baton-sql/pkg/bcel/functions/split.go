@@ -0,0 +1,56 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// Split splits s around each instance of sep. An empty string yields an empty list rather than a list with one empty
+// element, so empty columns map to no values.
+func Split(s string, sep string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, sep)
+}
+
+func SplitFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "split",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "split_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType},
+				ResultType: types.NewListType(types.StringType),
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					s, ok := lhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to split, expected string")
+					}
+					sep, ok := rhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid separator for split, expected string")
+					}
+					return types.NewStringList(types.DefaultTypeAdapter, Split(string(s), string(sep)))
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `split('admin,editor,viewer', ',')`,
+						Expected: []string{"admin", "editor", "viewer"},
+					},
+					{
+						Expr:     `split('', ',')`,
+						Expected: []string{},
+					},
+					{
+						Expr:     `split(.roles, ';')[1]`,
+						Expected: "editor",
+						Inputs:   map[string]any{"cols": map[string]any{"roles": "admin;editor"}},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package functions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Sep      string
+		Expected []string
+	}{
+		{"admin,editor,viewer", ",", []string{"admin", "editor", "viewer"}},
+		{"admin", ",", []string{"admin"}},
+		{"", ",", []string{}},
+		{"a,,b", ",", []string{"a", "", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			result := Split(test.Input, test.Sep)
+			if !slices.Equal(result, test.Expected) {
+				t.Errorf("Split(%q, %q) = %q; want %q", test.Input, test.Sep, result, test.Expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package functions
+
+import (
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// timeLayouts maps layout names that can be passed to parseTime and formatTime to Go time layouts.
+// Any other layout is used as a Go reference layout, e.g. '2006-01-02 15:04:05'.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+const day = 24 * time.Hour
+
+func timeLayout(layout string) string {
+	if l, ok := timeLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+// ParseTime parses value with the given layout. Values without a zone offset are in the tz location,
+// or UTC if tz is empty.
+func ParseTime(value string, layout string, tz string) (time.Time, error) {
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.ParseInLocation(timeLayout(layout), value, loc)
+}
+
+// FormatTime formats t with the given layout, in the tz location or UTC if tz is empty.
+func FormatTime(t time.Time, layout string, tz string) (string, error) {
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return t.In(loc).Format(timeLayout(layout)), nil
+}
+
+// DaysSince returns the number of whole days between t and now. It is negative for times in the future.
+func DaysSince(t time.Time, now time.Time) int64 {
+	return int64(now.Sub(t) / day)
+}
+
+func stringArgs(fn string, args ...ref.Val) ([]string, ref.Val) {
+	ret := make([]string, len(args))
+	for ii, arg := range args {
+		s, ok := arg.(types.String)
+		if !ok {
+			return nil, types.NewErr("invalid argument to %s, expected string", fn)
+		}
+		ret[ii] = string(s)
+	}
+	return ret, nil
+}
+
+func ParseTimeFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "parseTime",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "parseTime_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType},
+				ResultType: types.TimestampType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					args, errVal := stringArgs("parseTime", lhs, rhs)
+					if errVal != nil {
+						return errVal
+					}
+
+					t, err := ParseTime(args[0], args[1], "")
+					if err != nil {
+						return types.NewErr("parseTime: %w", err)
+					}
+					return types.Timestamp{Time: t}
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `parseTime('2025-04-17 14:30:45', 'DateTime')`,
+						Expected: time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC),
+					},
+					{
+						Expr:     `parseTime(.hired, '01/02/2006')`,
+						Expected: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+						Inputs:   map[string]any{"cols": map[string]any{"hired": "03/09/2024"}},
+					},
+					{
+						Expr:     `parseTime('2025-04-17T14:30:45+02:00', 'RFC3339') == timestamp('2025-04-17T12:30:45Z')`,
+						Expected: true,
+					},
+				},
+			},
+			{
+				Operator:   "parseTime_string_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType, types.StringType},
+				ResultType: types.TimestampType,
+				Function: func(values ...ref.Val) ref.Val {
+					if len(values) != 3 {
+						return types.NewErr("parseTime expects 2 or 3 arguments")
+					}
+					args, errVal := stringArgs("parseTime", values...)
+					if errVal != nil {
+						return errVal
+					}
+
+					t, err := ParseTime(args[0], args[1], args[2])
+					if err != nil {
+						return types.NewErr("parseTime: %w", err)
+					}
+					return types.Timestamp{Time: t}
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `parseTime('2025-01-15 09:00:00', 'DateTime', 'UTC') == timestamp('2025-01-15T09:00:00Z')`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func FormatTimeFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "formatTime",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "formatTime_timestamp_string",
+				Args:       []*types.Type{types.TimestampType, types.StringType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					t, ok := lhs.(types.Timestamp)
+					if !ok {
+						return types.NewErr("invalid argument to formatTime, expected timestamp")
+					}
+					layout, ok := rhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid layout for formatTime, expected string")
+					}
+
+					result, err := FormatTime(t.Time, string(layout), "")
+					if err != nil {
+						return types.NewErr("formatTime: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `formatTime(timestamp('2025-04-17T14:30:45Z'), 'DateOnly')`,
+						Expected: "2025-04-17",
+					},
+					{
+						Expr:     `formatTime(.last_login, 'Jan 2, 2006')`,
+						Expected: "Apr 17, 2025",
+						Inputs:   map[string]any{"cols": map[string]any{"last_login": time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)}},
+					},
+				},
+			},
+			{
+				Operator:   "formatTime_timestamp_string_string",
+				Args:       []*types.Type{types.TimestampType, types.StringType, types.StringType},
+				ResultType: types.StringType,
+				Function: func(values ...ref.Val) ref.Val {
+					if len(values) != 3 {
+						return types.NewErr("formatTime expects 2 or 3 arguments")
+					}
+					t, ok := values[0].(types.Timestamp)
+					if !ok {
+						return types.NewErr("invalid argument to formatTime, expected timestamp")
+					}
+					args, errVal := stringArgs("formatTime", values[1:]...)
+					if errVal != nil {
+						return errVal
+					}
+
+					result, err := FormatTime(t.Time, args[0], args[1])
+					if err != nil {
+						return types.NewErr("formatTime: %w", err)
+					}
+					return types.String(result)
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `formatTime(timestamp('2025-04-17T14:30:45Z'), 'DateTime', 'UTC')`,
+						Expected: "2025-04-17 14:30:45",
+					},
+				},
+			},
+		},
+	}
+}
+
+func UnixSecondsFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "unixSeconds",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "unixSeconds_timestamp",
+				Args:       []*types.Type{types.TimestampType},
+				ResultType: types.IntType,
+				Unary: func(v ref.Val) ref.Val {
+					t, ok := v.(types.Timestamp)
+					if !ok {
+						return types.NewErr("invalid argument to unixSeconds, expected timestamp")
+					}
+					return types.Int(t.Unix())
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `unixSeconds(timestamp('2025-04-17T14:30:45Z'))`,
+						Expected: int64(1744900245),
+					},
+				},
+			},
+		},
+	}
+}
+
+func UnixMillisFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "unixMillis",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "unixMillis_timestamp",
+				Args:       []*types.Type{types.TimestampType},
+				ResultType: types.IntType,
+				Unary: func(v ref.Val) ref.Val {
+					t, ok := v.(types.Timestamp)
+					if !ok {
+						return types.NewErr("invalid argument to unixMillis, expected timestamp")
+					}
+					return types.Int(t.UnixMilli())
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `unixMillis(timestamp('2025-04-17T14:30:45.123Z'))`,
+						Expected: int64(1744900245123),
+					},
+				},
+			},
+		},
+	}
+}
+
+func NowFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "now",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "now",
+				Args:       []*types.Type{},
+				ResultType: types.TimestampType,
+				Function: func(values ...ref.Val) ref.Val {
+					return types.Timestamp{Time: time.Now().UTC()}
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `now() > timestamp('2025-01-01T00:00:00Z')`,
+						Expected: true,
+					},
+					{
+						Expr:     `now() - duration('2160h') < now()`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func DaysFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "days",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "days_int",
+				Args:       []*types.Type{types.IntType},
+				ResultType: types.DurationType,
+				Unary: func(v ref.Val) ref.Val {
+					n, ok := v.(types.Int)
+					if !ok {
+						return types.NewErr("invalid argument to days, expected int")
+					}
+					return types.Duration{Duration: time.Duration(n) * day}
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `timestamp('2025-04-17T00:00:00Z') - days(90) == timestamp('2025-01-17T00:00:00Z')`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func DaysSinceFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "daysSince",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "daysSince_timestamp",
+				Args:       []*types.Type{types.TimestampType},
+				ResultType: types.IntType,
+				Unary: func(v ref.Val) ref.Val {
+					t, ok := v.(types.Timestamp)
+					if !ok {
+						return types.NewErr("invalid argument to daysSince, expected timestamp")
+					}
+					return types.Int(DaysSince(t.Time, time.Now()))
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `daysSince(.last_login) > 90`,
+						Expected: true,
+						Inputs:   map[string]any{"cols": map[string]any{"last_login": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}},
+					},
+					{
+						Expr:     `daysSince(now()) == 0`,
+						Expected: true,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package functions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Layout   string
+		TZ       string
+		Expected time.Time
+	}{
+		{"2025-04-17 14:30:45", "DateTime", "", time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)},
+		{"2025-04-17T14:30:45+02:00", "RFC3339", "", time.Date(2025, 4, 17, 12, 30, 45, 0, time.UTC)},
+		{"03/09/2024", "01/02/2006", "", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"2025-04-17 14:30:45", "DateTime", "UTC", time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Value, func(t *testing.T) {
+			result, err := ParseTime(test.Value, test.Layout, test.TZ)
+			if err != nil {
+				t.Fatalf("ParseTime(%q, %q, %q) returned error: %v", test.Value, test.Layout, test.TZ, err)
+			}
+			if !result.Equal(test.Expected) {
+				t.Errorf("ParseTime(%q, %q, %q) = %v; want %v", test.Value, test.Layout, test.TZ, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := ParseTime("not a time", "DateTime", ""); err == nil {
+		t.Errorf("ParseTime with a value that does not match the layout should return an error")
+	}
+
+	if _, err := ParseTime("2025-04-17", "DateOnly", "Not/AZone"); err == nil {
+		t.Errorf("ParseTime with an unknown time zone should return an error")
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		Layout   string
+		Expected string
+	}{
+		{"DateOnly", "2025-04-17"},
+		{"RFC3339", "2025-04-17T14:30:45Z"},
+		{"Jan 2, 2006", "Apr 17, 2025"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Layout, func(t *testing.T) {
+			result, err := FormatTime(ts, test.Layout, "")
+			if err != nil {
+				t.Fatalf("FormatTime(%q) returned error: %v", test.Layout, err)
+			}
+			if result != test.Expected {
+				t.Errorf("FormatTime(%q) = %q; want %q", test.Layout, result, test.Expected)
+			}
+		})
+	}
+}
+
+func TestDaysSince(t *testing.T) {
+	now := time.Date(2025, 4, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name     string
+		Time     time.Time
+		Expected int64
+	}{
+		{"same day", now.Add(-time.Hour), 0},
+		{"one day", now.Add(-25 * time.Hour), 1},
+		{"ninety days", now.AddDate(0, 0, -90), 90},
+		{"future", now.AddDate(0, 0, 2), -2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := DaysSince(test.Time, now); result != test.Expected {
+				t.Errorf("DaysSince() = %d; want %d", result, test.Expected)
+			}
+		})
+	}
+}
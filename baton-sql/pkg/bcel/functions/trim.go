@@ -0,0 +1,73 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// Trim removes leading and trailing characters in cutset from s, or leading and trailing whitespace if cutset is empty.
+func Trim(s string, cutset string) string {
+	if cutset == "" {
+		return strings.TrimSpace(s)
+	}
+	return strings.Trim(s, cutset)
+}
+
+func TrimFunc() *FunctionDefinition {
+	return &FunctionDefinition{
+		Name: "trim",
+		Overloads: []*OverloadDefinition{
+			{
+				Operator:   "trim_string",
+				Args:       []*types.Type{types.StringType},
+				ResultType: types.StringType,
+				Unary: func(v ref.Val) ref.Val {
+					s, ok := v.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to trim, expected string")
+					}
+					return types.String(Trim(string(s), ""))
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `trim('  jane  ')`,
+						Expected: "jane",
+					},
+					{
+						Expr:     `trim(.name)`,
+						Expected: "Jane Doe",
+						Inputs:   map[string]any{"cols": map[string]any{"name": "\tJane Doe\r\n"}},
+					},
+				},
+			},
+			{
+				Operator:   "trim_string_string",
+				Args:       []*types.Type{types.StringType, types.StringType},
+				ResultType: types.StringType,
+				Binary: func(lhs ref.Val, rhs ref.Val) ref.Val {
+					s, ok := lhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid argument to trim, expected string")
+					}
+					cutset, ok := rhs.(types.String)
+					if !ok {
+						return types.NewErr("invalid cutset for trim, expected string")
+					}
+					return types.String(Trim(string(s), string(cutset)))
+				},
+				TestCases: []*ExprTestCase{
+					{
+						Expr:     `trim('"quoted"', '"')`,
+						Expected: "quoted",
+					},
+					{
+						Expr:     `trim('--id--', '-')`,
+						Expected: "id",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package functions
+
+import "testing"
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Cutset   string
+		Expected string
+	}{
+		{"  jane  ", "", "jane"},
+		{"\tJane Doe\r\n", "", "Jane Doe"},
+		{"\"quoted\"", "\"", "quoted"},
+		{"--id--", "-", "id"},
+		{"xyhelloyx", "xy", "hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			result := Trim(test.Input, test.Cutset)
+			if result != test.Expected {
+				t.Errorf("Trim(%q, %q) = %q; want %q", test.Input, test.Cutset, result, test.Expected)
+			}
+		})
+	}
+}
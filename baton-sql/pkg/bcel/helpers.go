@@ -3,18 +3,26 @@ package bcel
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 )
 
-var dotFieldRegexp = regexp.MustCompile(`\.\w+`)
 var bareStringRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+var colsAccessRegexp = regexp.MustCompile(`cols\[('[^']*'|"[^"]*")\]`)
+var lookupCallRegexp = regexp.MustCompile(`\blookup\(\s*(?:'([^']*)'|"([^"]*)")`)
 
 func isAlphaNumeric(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
 }
 
+// isMemberAccess reports whether a dot following c selects a field or method of the preceding expression,
+// as in `user.name`, `lookup('departments', .dept_id).name` or `'a,b'.split(',')`, rather than starting a column reference.
+func isMemberAccess(c byte) bool {
+	return isAlphaNumeric(c) || c == ')' || c == ']' || c == '\'' || c == '"'
+}
+
 // preprocessExpressions replaces all column expressions with the appropriate map access.
-// It also detects 'bare strings' and automatically quotes them.
+// It also detects 'bare strings' and automatically quotes them. String literals are left untouched.
 // Example input: ".role_name == 'Admin'" -> "cols['role_name'] == 'Admin'".
 func preprocessExpressions(expr string) string {
 	if bareStringRegexp.MatchString(expr) {
@@ -25,20 +33,57 @@ func preprocessExpressions(expr string) string {
 		return fmt.Sprintf(`"%s"`, expr)
 	}
 
-	result := expr
-	offset := 0
+	var result strings.Builder
+	var quote byte
+
+	for ii := 0; ii < len(expr); ii++ {
+		c := expr[ii]
+
+		switch {
+		case quote != 0:
+			result.WriteByte(c)
+			if c == '\\' && ii+1 < len(expr) {
+				ii++
+				result.WriteByte(expr[ii])
+			} else if c == quote {
+				quote = 0
+			}
+
+		case c == '\'' || c == '"':
+			quote = c
+			result.WriteByte(c)
+
+		case c == '.' && ii+1 < len(expr) && isAlphaNumeric(expr[ii+1]) && (ii == 0 || !isMemberAccess(expr[ii-1])):
+			end := ii + 1
+			for end < len(expr) && isAlphaNumeric(expr[end]) {
+				end++
+			}
+			fmt.Fprintf(&result, "cols['%s']", expr[ii+1:end])
+			ii = end - 1
 
-	result = dotFieldRegexp.ReplaceAllStringFunc(result, func(s string) string {
-		matchIndex := strings.Index(expr[offset:], s) + offset
-		if matchIndex > 0 && isAlphaNumeric(expr[matchIndex-1]) {
-			offset = matchIndex + len(s)
-			return s
+		default:
+			result.WriteByte(c)
 		}
+	}
+
+	return result.String()
+}
 
-		offset = matchIndex + len(s)
-		field := strings.TrimPrefix(s, ".")
-		return fmt.Sprintf("cols['%s']", field)
-	})
+// lowerColumnAccess lower-cases the column name in every cols['name'] access.
+// Example input: "cols['USER_ID'] == 1" -> "cols['user_id'] == 1".
+func lowerColumnAccess(expr string) string {
+	return colsAccessRegexp.ReplaceAllStringFunc(expr, strings.ToLower)
+}
 
-	return result
+// lookupTableNames returns the table names passed to lookup() in an expression, in order of first use.
+// Example input: "lookup('departments', cols['dept_id']).name" -> ["departments"].
+func lookupTableNames(expr string) []string {
+	var ret []string
+	for _, m := range lookupCallRegexp.FindAllStringSubmatch(expr, -1) {
+		name := m[1] + m[2]
+		if !slices.Contains(ret, name) {
+			ret = append(ret, name)
+		}
+	}
+	return ret
 }
@@ -1,6 +1,9 @@
 package bcel
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func Test_preprocessExpressions(t *testing.T) {
 	tests := []struct {
@@ -32,6 +35,12 @@ func Test_preprocessExpressions(t *testing.T) {
 		{"Quoted string in expression", "user.role == 'admin'", "user.role == 'admin'"},
 		{"Function call with column access", "check_role(.role_name)", "check_role(cols['role_name'])"},
 		{"Bare string with existing quotes", "'alert'", "'alert'"},
+		{"Field access on function result", "lookup('departments', .dept_id).name", "lookup('departments', cols['dept_id']).name"},
+		{"Field access on index result", "person['manager'].name == .name", "person['manager'].name == cols['name']"},
+		{"Method call on string literal", "'Admin'.lowerAscii() == .role", "'Admin'.lowerAscii() == cols['role']"},
+		{"Dots inside string literals", "jsonPath(.attrs, '$.roles[*]') + \".name\"", "jsonPath(cols['attrs'], '$.roles[*]') + \".name\""},
+		{"Escaped quote inside string literal", "'it\\'s .here' + .there", "'it\\'s .here' + cols['there']"},
+		{"Decimal literal", ".score > 1.5", "cols['score'] > 1.5"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -41,3 +50,42 @@ func Test_preprocessExpressions(t *testing.T) {
 		})
 	}
 }
+
+func Test_lowerColumnAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"Single quoted access", "cols['USER_ID']", "cols['user_id']"},
+		{"Double quoted access", `cols["User_Id"] == 'Admin'`, `cols["user_id"] == 'Admin'`},
+		{"String literals are untouched", "cols['ROLE'] + ' ROLE'", "cols['role'] + ' ROLE'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lowerColumnAccess(preprocessExpressions(tt.expr)); got != tt.want {
+				t.Errorf("lowerColumnAccess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_lookupTableNames(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{"No lookups", "cols['dept_id']", nil},
+		{"Single lookup", "lookup('departments', cols['dept_id']).name", []string{"departments"}},
+		{"Repeated and double quoted lookups", `lookup("users", cols['manager_id']).email + lookup('departments', 1).name + lookup('users', 2).email`, []string{"users", "departments"}},
+		{"Other functions ending in lookup", "myLookup('departments', 1)", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookupTableNames(tt.expr); !slices.Equal(got, tt.want) {
+				t.Errorf("lookupTableNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package bcel
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+)
+
+var functionSignatureRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\(([^()]*)\)\s*$`)
+
+// reservedFunctionNames are the CEL macros that user-defined functions may not replace.
+var reservedFunctionNames = []string{"has", "all", "exists", "exists_one", "map", "filter"}
+
+// reservedParamNames are the environment variables that parameters may not shadow. A parameter named cols would also
+// take over the column accesses (.name) in the body.
+var reservedParamNames = []string{"cols", "resource", "principal", "entitlement", "grant"}
+
+// userFunction is a named, parameterized expression from the config. Calls to it are expanded inline when an
+// expression is compiled, so it can be used anywhere a CEL expression is accepted.
+type userFunction struct {
+	name      string
+	signature string
+	params    []string
+	body      string
+	calls     []string
+	lookups   []string
+	callRegex *regexp.Regexp
+}
+
+// parseFunctionSignature parses a signature such as `normLogin(s)` into the function name and parameter names.
+func parseFunctionSignature(signature string) (string, []string, error) {
+	m := functionSignatureRegexp.FindStringSubmatch(signature)
+	if m == nil {
+		return "", nil, fmt.Errorf("invalid function signature %q, expected name(param, ...)", signature)
+	}
+
+	var params []string
+	if strings.TrimSpace(m[2]) != "" {
+		for _, p := range strings.Split(m[2], ",") {
+			p = strings.TrimSpace(p)
+			if !bareStringRegexp.MatchString(p) {
+				return "", nil, fmt.Errorf("invalid parameter name %q in function signature %q", p, signature)
+			}
+			if slices.Contains(reservedParamNames, p) {
+				return "", nil, fmt.Errorf("parameter name %q in function signature %q conflicts with a built-in variable", p, signature)
+			}
+			if slices.Contains(params, p) {
+				return "", nil, fmt.Errorf("duplicate parameter name %q in function signature %q", p, signature)
+			}
+			params = append(params, p)
+		}
+	}
+
+	return m[1], params, nil
+}
+
+// newUserFunctions parses the function definitions and orders them so that every function comes after the functions
+// it calls. It returns an error if a function calls itself, directly or through other functions.
+func newUserFunctions(env *cel.Env, defs map[string]string, caseInsensitiveColumns bool) ([]*userFunction, error) {
+	byName := make(map[string]*userFunction, len(defs))
+	var names []string
+	for _, signature := range slices.Sorted(maps.Keys(defs)) {
+		name, params, err := parseFunctionSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byName[name]; ok {
+			return nil, fmt.Errorf("function %s is defined more than once", name)
+		}
+		if env.HasFunction(name) || slices.Contains(reservedFunctionNames, name) {
+			return nil, fmt.Errorf("function %s conflicts with a built-in function", name)
+		}
+
+		body := defs[signature]
+		if !slices.Contains(params, strings.TrimSpace(body)) {
+			body = preprocessExpressions(body)
+			if caseInsensitiveColumns {
+				body = lowerColumnAccess(body)
+			}
+		}
+
+		names = append(names, name)
+		byName[name] = &userFunction{
+			name:      name,
+			signature: signature,
+			params:    params,
+			body:      body,
+			callRegex: regexp.MustCompile(`(^|[^a-zA-Z0-9_.])` + regexp.QuoteMeta(name) + `\s*\(`),
+		}
+	}
+
+	for _, name := range names {
+		fn := byName[name]
+		parsed, iss := env.Parse(fn.body)
+		if iss != nil && iss.Err() != nil {
+			return nil, fmt.Errorf("function %s: %w", fn.signature, iss.Err())
+		}
+
+		var errs []string
+		ast.PostOrderVisit(parsed.NativeRep().Expr(), ast.NewExprVisitor(func(e ast.Expr) {
+			switch e.Kind() {
+			case ast.CallKind:
+				call := e.AsCall()
+				if _, ok := byName[call.FunctionName()]; ok && !call.IsMemberFunction() && !slices.Contains(fn.calls, call.FunctionName()) {
+					fn.calls = append(fn.calls, call.FunctionName())
+				}
+			case ast.ComprehensionKind:
+				comp := e.AsComprehension()
+				for _, v := range []string{comp.IterVar(), comp.IterVar2(), comp.AccuVar()} {
+					if slices.Contains(fn.params, v) {
+						errs = append(errs, fmt.Sprintf("parameter %s is shadowed by a comprehension variable", v))
+					}
+				}
+			}
+		}))
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("function %s: %s", fn.signature, errs[0])
+		}
+	}
+
+	// Order the functions depth-first by their calls, reporting the call path of any cycle.
+	var ordered []*userFunction
+	done := make(map[string]bool, len(byName))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if done[name] {
+			return nil
+		}
+		if idx := slices.Index(path, name); idx != -1 {
+			return fmt.Errorf("function %s is recursive: %s", name, strings.Join(append(path[idx:], name), " -> "))
+		}
+
+		fn := byName[name]
+		path = append(path, name)
+		for _, callee := range fn.calls {
+			if err := visit(callee, path); err != nil {
+				return err
+			}
+		}
+
+		fn.lookups = lookupTableNames(fn.body)
+		for _, callee := range fn.calls {
+			for _, table := range byName[callee].lookups {
+				if !slices.Contains(fn.lookups, table) {
+					fn.lookups = append(fn.lookups, table)
+				}
+			}
+		}
+
+		done[name] = true
+		ordered = append(ordered, fn)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// withUserFunctions extends env with a macro for each function. Functions are parsed in order, so the calls in each
+// body to previously defined functions are already expanded.
+func withUserFunctions(env *cel.Env, fns []*userFunction) (*cel.Env, error) {
+	for _, fn := range fns {
+		parsed, iss := env.Parse(fn.body)
+		if iss != nil && iss.Err() != nil {
+			return nil, fmt.Errorf("function %s: %w", fn.signature, iss.Err())
+		}
+
+		var err error
+		env, err = env.Extend(cel.Macros(cel.GlobalMacro(fn.name, len(fn.params), fn.expander(parsed.NativeRep().Expr()))))
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", fn.signature, err)
+		}
+	}
+
+	return env, nil
+}
+
+// expander returns a macro expander that replaces a call with a copy of body, with each parameter replaced by the
+// matching argument. The comprehension variables of the body are renamed first, so they cannot capture variables of
+// the same name in the arguments.
+func (f *userFunction) expander(body ast.Expr) cel.MacroFactory {
+	return func(mef cel.MacroExprFactory, target ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+		ret := mef.Copy(body)
+		f.renameComprehensionVars(mef, ret)
+
+		// Visiting bottom-up means the substituted arguments are not visited themselves.
+		ast.PostOrderVisit(ret, ast.NewExprVisitor(func(e ast.Expr) {
+			if e.Kind() != ast.IdentKind {
+				return
+			}
+			if idx := slices.Index(f.params, e.AsIdent()); idx != -1 {
+				e.SetKindCase(mef.Copy(args[idx]))
+			}
+		}))
+
+		return ret, nil
+	}
+}
+
+// renameComprehensionVars gives the comprehension variables in expr names that cannot be written in an expression.
+// Inner comprehensions are renamed first, so the identifiers left in an outer comprehension that use its variable
+// names refer to it.
+func (f *userFunction) renameComprehensionVars(mef cel.MacroExprFactory, expr ast.Expr) {
+	var comps []ast.Expr
+	ast.PostOrderVisit(expr, ast.NewExprVisitor(func(e ast.Expr) {
+		if e.Kind() == ast.ComprehensionKind {
+			comps = append(comps, e)
+		}
+	}))
+
+	for _, e := range comps {
+		comp := e.AsComprehension()
+		iterVar := f.hiddenVarName(comp.IterVar())
+		iterVar2 := f.hiddenVarName(comp.IterVar2())
+		for _, scope := range []ast.Expr{comp.LoopCondition(), comp.LoopStep()} {
+			ast.PostOrderVisit(scope, ast.NewExprVisitor(func(v ast.Expr) {
+				if v.Kind() != ast.IdentKind {
+					return
+				}
+				switch v.AsIdent() {
+				case comp.IterVar():
+					v.SetKindCase(mef.NewIdent(iterVar))
+				case comp.IterVar2():
+					v.SetKindCase(mef.NewIdent(iterVar2))
+				}
+			}))
+		}
+		e.SetKindCase(mef.NewComprehensionTwoVar(comp.IterRange(), iterVar, iterVar2, comp.AccuVar(), comp.AccuInit(),
+			comp.LoopCondition(), comp.LoopStep(), comp.Result()))
+	}
+}
+
+// hiddenVarName returns the name a comprehension variable of the function is renamed to.
+func (f *userFunction) hiddenVarName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "@" + f.name + "_" + name
+}
+
+// calledIn reports whether expr calls the function.
+func (f *userFunction) calledIn(expr string) bool {
+	return f.callRegex.MatchString(expr)
+}
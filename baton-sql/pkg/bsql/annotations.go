@@ -0,0 +1,96 @@
+package bsql
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// annotationTarget is the kind of object an annotations mapping belongs to.
+type annotationTarget string
+
+const (
+	annotationTargetResource    annotationTarget = "resource"
+	annotationTargetEntitlement annotationTarget = "entitlement"
+	annotationTargetGrant       annotationTarget = "grant"
+)
+
+// mapAnnotations evaluates the annotations mapping of a resource, entitlement or grant and adds the resulting
+// annotations to annos. Annotations that don't apply to the target, such as grant_immutable on a resource mapping,
+// are rejected.
+func (s *SQLSyncer) mapAnnotations(
+	ctx context.Context,
+	target annotationTarget,
+	annos *annotations.Annotations,
+	mapping *Annotations,
+	inputs map[string]any,
+) error {
+	if mapping == nil {
+		return nil
+	}
+
+	if mapping.EntitlementImmutable != nil && target != annotationTargetEntitlement {
+		return fmt.Errorf("entitlement_immutable is only valid on entitlement mappings, not on %s mappings", target)
+	}
+	if mapping.GrantImmutable != nil && target != annotationTargetGrant {
+		return fmt.Errorf("grant_immutable is only valid on grant mappings, not on %s mappings", target)
+	}
+
+	if mapping.ExternalLink != nil && mapping.ExternalLink.Url != "" {
+		url, err := s.env.EvaluateString(ctx, mapping.ExternalLink.Url, inputs)
+		if err != nil {
+			return err
+		}
+		if url != "" {
+			annos.Update(&v2.ExternalLink{Url: url})
+		}
+	}
+
+	if mapping.EntitlementImmutable != nil {
+		immutable, sourceID, err := s.evaluateImmutable(ctx, mapping.EntitlementImmutable, inputs)
+		if err != nil {
+			return err
+		}
+		if immutable {
+			annos.Update(&v2.EntitlementImmutable{SourceId: sourceID})
+		}
+	}
+
+	if mapping.GrantImmutable != nil {
+		immutable, sourceID, err := s.evaluateImmutable(ctx, mapping.GrantImmutable, inputs)
+		if err != nil {
+			return err
+		}
+		if immutable {
+			annos.Update(&v2.GrantImmutable{SourceId: sourceID})
+		}
+	}
+
+	return nil
+}
+
+// evaluateImmutable reports whether an immutable annotation applies to the row and evaluates its source ID.
+func (s *SQLSyncer) evaluateImmutable(ctx context.Context, mapping *ImmutableAnnotation, inputs map[string]any) (bool, string, error) {
+	if mapping.If != "" {
+		immutable, err := s.env.EvaluateBool(ctx, mapping.If, inputs)
+		if err != nil {
+			return false, "", err
+		}
+		if !immutable {
+			return false, "", nil
+		}
+	}
+
+	var sourceID string
+	if mapping.SourceId != "" {
+		var err error
+		sourceID, err = s.env.EvaluateString(ctx, mapping.SourceId, inputs)
+		if err != nil {
+			return false, "", err
+		}
+	}
+
+	return true, sourceID, nil
+}
@@ -0,0 +1,76 @@
+package bsql
+
+import (
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+)
+
+func TestSQLSyncer_mapAnnotations(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+	s := &SQLSyncer{env: env}
+
+	mapping := &Annotations{
+		ExternalLink:         &ExternalLinkAnnotation{Url: "'https://admin.example.com/roles/' + string(.id)"},
+		EntitlementImmutable: &ImmutableAnnotation{If: ".is_system", SourceId: "'builtin'"},
+	}
+
+	t.Run("Per-row values", func(t *testing.T) {
+		var annos annotations.Annotations
+		inputs := env.SyncInputs(map[string]any{"id": int64(7), "is_system": true})
+		require.NoError(t, s.mapAnnotations(ctx, annotationTargetEntitlement, &annos, mapping, inputs))
+
+		link := &v2.ExternalLink{}
+		ok, err := annos.Pick(link)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "https://admin.example.com/roles/7", link.GetUrl())
+
+		immutable := &v2.EntitlementImmutable{}
+		ok, err = annos.Pick(immutable)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "builtin", immutable.GetSourceId())
+	})
+
+	t.Run("Condition not met", func(t *testing.T) {
+		var annos annotations.Annotations
+		inputs := env.SyncInputs(map[string]any{"id": int64(8), "is_system": false})
+		require.NoError(t, s.mapAnnotations(ctx, annotationTargetEntitlement, &annos, mapping, inputs))
+		require.False(t, annos.Contains(&v2.EntitlementImmutable{}))
+		require.True(t, annos.Contains(&v2.ExternalLink{}))
+	})
+
+	t.Run("Empty link is skipped", func(t *testing.T) {
+		var annos annotations.Annotations
+		inputs := env.SyncInputs(map[string]any{})
+		require.NoError(t, s.mapAnnotations(ctx, annotationTargetGrant, &annos, &Annotations{
+			ExternalLink:   &ExternalLinkAnnotation{Url: "''"},
+			GrantImmutable: &ImmutableAnnotation{},
+		}, inputs))
+		require.False(t, annos.Contains(&v2.ExternalLink{}))
+		require.True(t, annos.Contains(&v2.GrantImmutable{}))
+	})
+
+	t.Run("Misplaced immutable annotations", func(t *testing.T) {
+		var annos annotations.Annotations
+		inputs := env.SyncInputs(map[string]any{"id": int64(7), "is_system": true})
+
+		err := s.mapAnnotations(ctx, annotationTargetResource, &annos, mapping, inputs)
+		require.EqualError(t, err, "entitlement_immutable is only valid on entitlement mappings, not on resource mappings")
+
+		err = s.mapAnnotations(ctx, annotationTargetEntitlement, &annos, &Annotations{GrantImmutable: &ImmutableAnnotation{}}, inputs)
+		require.EqualError(t, err, "grant_immutable is only valid on grant mappings, not on entitlement mappings")
+
+		err = s.mapAnnotations(ctx, annotationTargetGrant, &annos, mapping, inputs)
+		require.EqualError(t, err, "entitlement_immutable is only valid on entitlement mappings, not on grant mappings")
+		require.Empty(t, annos)
+	})
+}
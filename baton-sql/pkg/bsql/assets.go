@@ -0,0 +1,179 @@
+package bsql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+const (
+	// assetIDSeparator separates the resource type from the value of the icon mapping in asset IDs, so the asset
+	// query can be found from the ID alone.
+	assetIDSeparator = ":"
+	assetIDKey       = "asset_id"
+	// sniffLen is the most bytes http.DetectContentType considers.
+	sniffLen = 512
+)
+
+var ErrAssetNotFound = errors.New("asset not found")
+
+// mapIcon evaluates an icon mapping into a reference to an asset of the resource type.
+func (s *SQLSyncer) mapIcon(ctx context.Context, mapping string, inputs map[string]any) (*v2.AssetRef, error) {
+	if mapping == "" {
+		return nil, nil
+	}
+
+	if s.config.Asset == nil {
+		return nil, fmt.Errorf("resource type %s has an icon mapping but no asset query", s.resourceType.Id)
+	}
+
+	v, err := s.env.EvaluateString(ctx, mapping, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if v == "" {
+		return nil, nil
+	}
+
+	return &v2.AssetRef{Id: s.resourceType.Id + assetIDSeparator + v}, nil
+}
+
+// GetAsset fetches an asset referenced by an icon mapping. It returns the content type of the asset and a reader for
+// its contents, which the SDK sends to the client as a metadata message followed by chunked data messages.
+func (c Config) GetAsset(ctx context.Context, db *sql.DB, dbEngine database.DbEngine, celEnv *bcel.Env, asset *v2.AssetRef) (string, io.ReadCloser, error) {
+	rtID, assetID, ok := strings.Cut(asset.GetId(), assetIDSeparator)
+	if !ok {
+		return "", nil, fmt.Errorf("invalid asset ID %s", asset.GetId())
+	}
+
+	rtConfig, ok := c.ResourceTypes[rtID]
+	if !ok || rtConfig.Asset == nil {
+		return "", nil, fmt.Errorf("resource type %s has no asset query", rtID)
+	}
+
+	// Value normalization would hex-encode binary asset data, so binary columns are read as the driver returns them.
+	if c.Normalize != nil {
+		normalize := *c.Normalize
+		normalize.rawBinary = true
+		c.Normalize = &normalize
+	}
+
+	s := &SQLSyncer{
+		resourceType: &v2.ResourceType{Id: rtID},
+		config:       rtConfig,
+		db:           db,
+		dbEngine:     dbEngine,
+		env:          celEnv,
+		fullConfig:   c,
+	}
+
+	return s.fetchAsset(ctx, assetID)
+}
+
+func (s *SQLSyncer) fetchAsset(ctx context.Context, assetID string) (string, io.ReadCloser, error) {
+	aq := s.config.Asset
+
+	var row map[string]any
+	_, err := s.runQuery(ctx, nil, aq.Query.String(), nil, map[string]any{assetIDKey: assetID}, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+		row = rowMap
+		return false, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if row == nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrAssetNotFound, assetID)
+	}
+
+	inputs := s.env.SyncInputs(row)
+
+	var contentType string
+	if aq.ContentType != "" {
+		contentType, err = s.env.EvaluateString(ctx, aq.ContentType, inputs)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	switch {
+	case aq.Data != "":
+		out, err := s.env.Evaluate(ctx, aq.Data, inputs)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var data []byte
+		switch v := out.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			return "", nil, fmt.Errorf("asset data must be bytes or a string, got %T", out)
+		}
+
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		return contentType, io.NopCloser(bytes.NewReader(data)), nil
+
+	case aq.Path != "":
+		path, err := s.env.EvaluateString(ctx, aq.Path, inputs)
+		if err != nil {
+			return "", nil, err
+		}
+
+		f, err := openAssetFile(aq.Root, path)
+		if err != nil {
+			return "", nil, err
+		}
+
+		r := bufio.NewReaderSize(f, sniffLen)
+		if contentType == "" {
+			// A file shorter than sniffLen is detected from its full contents.
+			head, err := r.Peek(sniffLen)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return "", nil, errors.Join(err, f.Close())
+			}
+			contentType = http.DetectContentType(head)
+		}
+		return contentType, &assetFile{Reader: r, Closer: f}, nil
+
+	default:
+		return "", nil, fmt.Errorf("asset query for resource type %s requires data or path", s.resourceType.Id)
+	}
+}
+
+// openAssetFile opens a file within root. Leading slashes are ignored, so paths stored relative to a web root can be
+// used as they are.
+func openAssetFile(root string, path string) (*os.File, error) {
+	if root == "" {
+		return nil, errors.New("asset root is required to read asset paths")
+	}
+
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return r.Open(strings.TrimLeft(path, "/"))
+}
+
+// assetFile reads through a buffered reader and closes the underlying file.
+type assetFile struct {
+	io.Reader
+	io.Closer
+}
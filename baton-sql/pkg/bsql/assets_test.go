@@ -0,0 +1,140 @@
+package bsql
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+// pngHeader is enough of a PNG file for its content type to be detected.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestConfig_GetAsset(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER, avatar BLOB, avatar_type TEXT)",
+		"CREATE TABLE apps (id INTEGER, logo_path TEXT)",
+	)
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "logos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "logos", "app.svg"), []byte("<svg></svg>"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(t.TempDir(), "secret.txt"), []byte("secret"), 0o600))
+
+	_, err := db.ExecContext(ctx, "INSERT INTO users VALUES (1, ?, NULL), (2, ?, 'image/gif')", pngHeader, []byte("GIF89a"))
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO apps VALUES (1, '/logos/app.svg'), (2, '../secret.txt')")
+	require.NoError(t, err)
+
+	c := Config{
+		ResourceTypes: map[string]ResourceType{
+			"user": {
+				Asset: &AssetQuery{
+					Query:       Query{Default: "SELECT avatar, avatar_type FROM users WHERE id = ?<asset_id>"},
+					Data:        ".avatar",
+					ContentType: "default(.avatar_type, '')",
+				},
+			},
+			"app": {
+				Asset: &AssetQuery{
+					Query:       Query{Default: "SELECT logo_path FROM apps WHERE id = ?<asset_id>"},
+					Path:        ".logo_path",
+					Root:        root,
+					ContentType: "'image/svg+xml'",
+				},
+			},
+			"group": {},
+		},
+	}
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	getAsset := func(id string) (string, []byte, error) {
+		contentType, r, err := c.GetAsset(ctx, db, database.SQLite, env, &v2.AssetRef{Id: id})
+		if err != nil {
+			return "", nil, err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		return contentType, data, err
+	}
+
+	contentType, data, err := getAsset("user:1")
+	require.NoError(t, err)
+	require.Equal(t, "image/png", contentType)
+	require.Equal(t, pngHeader, data)
+
+	contentType, _, err = getAsset("user:2")
+	require.NoError(t, err)
+	require.Equal(t, "image/gif", contentType)
+
+	contentType, data, err = getAsset("app:1")
+	require.NoError(t, err)
+	require.Equal(t, "image/svg+xml", contentType)
+	require.Equal(t, "<svg></svg>", string(data))
+
+	_, _, err = getAsset("user:3")
+	require.ErrorIs(t, err, ErrAssetNotFound)
+
+	_, _, err = getAsset("app:2")
+	require.Error(t, err, "paths outside of the root cannot be read")
+
+	_, _, err = getAsset("group:1")
+	require.ErrorContains(t, err, "resource type group has no asset query")
+
+	_, _, err = getAsset("user")
+	require.ErrorContains(t, err, "invalid asset ID")
+
+	// Binary data is served as bytes even when value normalization would turn it into hex.
+	c.Normalize = &NormalizeConfig{Values: true, CaseInsensitiveColumns: true}
+
+	contentType, data, err = getAsset("user:1")
+	require.NoError(t, err)
+	require.Equal(t, "image/png", contentType)
+	require.Equal(t, pngHeader, data)
+
+	contentType, _, err = getAsset("user:2")
+	require.NoError(t, err)
+	require.Equal(t, "image/gif", contentType)
+	require.False(t, c.Normalize.rawBinary)
+}
+
+func TestSQLSyncer_mapIcon(t *testing.T) {
+	ctx := t.Context()
+
+	s := newTestSyncer(t, nil, "user", ResourceType{
+		List: &ListQuery{
+			Map: &ResourceMapping{
+				Traits: &Traits{User: &UserTraitMapping{Icon: ".has_avatar ? string(.id) : ''"}},
+			},
+		},
+		Asset: &AssetQuery{Query: Query{Default: "SELECT avatar FROM users WHERE id = ?<asset_id>"}, Data: ".avatar"},
+	}, Config{})
+
+	r := &v2.Resource{}
+	require.NoError(t, s.mapUserTrait(ctx, r, map[string]any{"id": int64(7), "has_avatar": true}))
+	ut, err := sdkResource.GetUserTrait(r)
+	require.NoError(t, err)
+	require.Equal(t, "user:7", ut.GetIcon().GetId())
+
+	r = &v2.Resource{}
+	require.NoError(t, s.mapUserTrait(ctx, r, map[string]any{"id": int64(8), "has_avatar": false}))
+	ut, err = sdkResource.GetUserTrait(r)
+	require.NoError(t, err)
+	require.Nil(t, ut.GetIcon())
+
+	s.config.Asset = nil
+	err = s.mapUserTrait(ctx, &v2.Resource{}, map[string]any{"id": int64(7), "has_avatar": true})
+	require.ErrorContains(t, err, "has an icon mapping but no asset query")
+}
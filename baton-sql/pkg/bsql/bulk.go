@@ -0,0 +1,412 @@
+package bsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+const defaultBulkMaxMemoryRows = 50000
+
+// registerGobTypes registers the concrete column value types that are gob-encoded as interface values on spill.
+var registerGobTypes = sync.OnceFunc(func() {
+	gob.Register(time.Time{})
+	gob.Register([]any{})
+	gob.Register(map[string]any{})
+	gob.Register([16]byte{})
+})
+
+// bulkQuery describes a query that runs once for all resources of a type.
+type bulkQuery struct {
+	// key identifies the query configuration the results are cached for.
+	key        any
+	query      string
+	pagination *Pagination
+	vars       map[string]string
+	resourceID string
+}
+
+// bulkRows returns a page of the rows a bulk query produced for the given resource, and the token for the next page.
+// The query runs on the first request and its results are kept until ResetBulkResults is called, which the connector
+// does at the start of every sync, so a page that is requested again returns the same rows.
+func (s *SQLSyncer) bulkRows(ctx context.Context, bq *bulkQuery, resource *v2.Resource, pToken *pagination.Token) ([]map[string]any, string, error) {
+	if bq.resourceID == "" {
+		return nil, "", errors.New("resource_id mapping is required for bulk queries")
+	}
+
+	offset := 0
+	if pToken != nil && pToken.Token != "" {
+		var err error
+		offset, err = strconv.Atoi(pToken.Token)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse bulk page token %s: %w", pToken.Token, err)
+		}
+	}
+
+	pageSize := defaultPageSize
+	if pToken != nil {
+		pageSize = int(clampPageSize(pToken.Size))
+	}
+
+	s.bulkMtx.Lock()
+	defer s.bulkMtx.Unlock()
+
+	resourceID := resource.GetId().GetResource()
+
+	if s.bulkResults == nil {
+		s.bulkResults = make(map[any]*bulkRowStore)
+	}
+
+	store := s.bulkResults[bq.key]
+	if store == nil {
+		var err error
+		store, err = s.loadBulkResult(ctx, bq)
+		if err != nil {
+			return nil, "", err
+		}
+		s.bulkResults[bq.key] = store
+	}
+
+	rows, more, err := store.Rows(ctx, resourceID, offset, pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !more {
+		return rows, "", nil
+	}
+
+	return rows, strconv.Itoa(offset + len(rows)), nil
+}
+
+// loadBulkResult runs every page of a bulk query and partitions the rows by resource ID.
+func (s *SQLSyncer) loadBulkResult(ctx context.Context, bq *bulkQuery) (*bulkRowStore, error) {
+	l := ctxzap.Extract(ctx)
+
+	maxMemoryRows := defaultBulkMaxMemoryRows
+	spillDir := ""
+	if s.fullConfig.BulkCache != nil {
+		if s.fullConfig.BulkCache.MaxMemoryRows > 0 {
+			maxMemoryRows = s.fullConfig.BulkCache.MaxMemoryRows
+		}
+		spillDir = s.fullConfig.BulkCache.Dir
+	}
+
+	store := newBulkRowStore(maxMemoryRows, spillDir)
+
+	queryVars, err := s.prepareQueryVars(ctx, s.env.SyncInputs(nil), bq.vars)
+	if err != nil {
+		return nil, err
+	}
+
+	pToken := &pagination.Token{Size: maxPageSize}
+	for {
+		npt, err := s.runQuery(ctx, pToken, bq.query, bq.pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+			resourceID, err := s.env.EvaluateString(ctx, bq.resourceID, s.env.SyncInputs(rowMap))
+			if err != nil {
+				return false, err
+			}
+
+			if err := store.Append(ctx, resourceID, rowMap); err != nil {
+				return false, err
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, errors.Join(err, store.Close())
+		}
+
+		if npt == "" {
+			break
+		}
+		pToken = &pagination.Token{Size: maxPageSize, Token: npt}
+	}
+
+	l.Debug("loaded bulk query results", zap.Int("rows", store.Len()), zap.Bool("spilled", store.disk != nil))
+
+	return store, nil
+}
+
+// ResetBulkResults drops the cached bulk query results, including any spill files, so that each bulk query runs again
+// the next time it is used.
+func (s *SQLSyncer) ResetBulkResults() error {
+	s.bulkMtx.Lock()
+	defer s.bulkMtx.Unlock()
+
+	var errs error
+	for key, store := range s.bulkResults {
+		errs = errors.Join(errs, store.Close())
+		delete(s.bulkResults, key)
+	}
+	return errs
+}
+
+// bulkRowStore holds rows partitioned by resource ID. Rows are kept in memory until maxMemoryRows is reached,
+// after which all rows are moved to a temporary SQLite database.
+type bulkRowStore struct {
+	maxMemoryRows int
+	dir           string
+
+	memory map[string][]map[string]any
+	count  int
+	disk   *diskRowStore
+}
+
+func newBulkRowStore(maxMemoryRows int, dir string) *bulkRowStore {
+	return &bulkRowStore{
+		maxMemoryRows: maxMemoryRows,
+		dir:           dir,
+		memory:        make(map[string][]map[string]any),
+	}
+}
+
+func (b *bulkRowStore) Len() int {
+	return b.count
+}
+
+// Append adds a row for the resource. Its values are converted with bulkValue first, so rows read the same whether
+// or not the store has spilled to disk.
+func (b *bulkRowStore) Append(ctx context.Context, resourceID string, row map[string]any) error {
+	row, err := bulkRow(row)
+	if err != nil {
+		return err
+	}
+
+	b.count++
+
+	if b.disk == nil && b.count > b.maxMemoryRows {
+		disk, err := newDiskRowStore(ctx, b.dir)
+		if err != nil {
+			return err
+		}
+		b.disk = disk
+
+		for id, rows := range b.memory {
+			for _, r := range rows {
+				if err := b.disk.Append(ctx, id, r); err != nil {
+					return err
+				}
+			}
+		}
+		b.memory = nil
+	}
+
+	if b.disk != nil {
+		return b.disk.Append(ctx, resourceID, row)
+	}
+
+	b.memory[resourceID] = append(b.memory[resourceID], row)
+	return nil
+}
+
+// Rows returns up to limit rows for the resource starting at offset, and whether more rows remain.
+func (b *bulkRowStore) Rows(ctx context.Context, resourceID string, offset int, limit int) ([]map[string]any, bool, error) {
+	if b.disk != nil {
+		return b.disk.Rows(ctx, resourceID, offset, limit)
+	}
+
+	rows := b.memory[resourceID]
+	if offset >= len(rows) {
+		return nil, false, nil
+	}
+
+	end := min(offset+limit, len(rows))
+	return rows[offset:end], end < len(rows), nil
+}
+
+func (b *bulkRowStore) Close() error {
+	b.memory = nil
+	if b.disk != nil {
+		return b.disk.Close()
+	}
+	return nil
+}
+
+func bulkRow(row map[string]any) (map[string]any, error) {
+	ret := make(map[string]any, len(row))
+	for k, v := range row {
+		bv, err := bulkValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", k, err)
+		}
+		ret[k] = bv
+	}
+	return ret, nil
+}
+
+// bulkValue converts a column value to one of the types the spill file can encode: nil, string, []byte, bool,
+// int64, uint64, float64, time.Time, [16]byte, []any or map[string]any. Driver-specific types are converted with
+// their driver.Valuer implementation, or else their String method.
+func bulkValue(val any) (any, error) {
+	switch v := val.(type) {
+	case nil, string, []byte, bool, int64, uint64, float64, time.Time, [16]byte:
+		return v, nil
+
+	case []any:
+		ret := make([]any, len(v))
+		for i, item := range v {
+			bv, err := bulkValue(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = bv
+		}
+		return ret, nil
+
+	case map[string]any:
+		return bulkRow(v)
+
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return nil, err
+		}
+		return bulkValue(dv)
+
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	default:
+		return nil, fmt.Errorf("unsupported bulk query value type %T", val)
+	}
+}
+
+// diskRow is the gob-encoded form of a row. Gob cannot encode nil interface values, so NULL columns are listed separately.
+type diskRow struct {
+	Values map[string]any
+	Nulls  []string
+}
+
+// diskRowStore keeps partitioned rows in a temporary SQLite database that is removed on Close.
+type diskRowStore struct {
+	dir string
+	db  *sql.DB
+}
+
+func newDiskRowStore(ctx context.Context, dir string) (*diskRowStore, error) {
+	registerGobTypes()
+
+	tmpDir, err := os.MkdirTemp(dir, "baton-sql-bulk-")
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(tmpDir, "rows.db"))
+	if err != nil {
+		return nil, errors.Join(err, os.RemoveAll(tmpDir))
+	}
+	db.SetMaxOpenConns(1)
+
+	ret := &diskRowStore{
+		dir: tmpDir,
+		db:  db,
+	}
+
+	for _, stmt := range []string{
+		"PRAGMA journal_mode = OFF",
+		"PRAGMA synchronous = OFF",
+		"CREATE TABLE rows (seq INTEGER PRIMARY KEY, resource_id TEXT NOT NULL, data BLOB NOT NULL)",
+		"CREATE INDEX rows_resource_id ON rows (resource_id, seq)",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, errors.Join(err, ret.Close())
+		}
+	}
+
+	return ret, nil
+}
+
+func (d *diskRowStore) Append(ctx context.Context, resourceID string, row map[string]any) error {
+	dr := diskRow{Values: make(map[string]any, len(row))}
+	for k, v := range row {
+		if v == nil {
+			dr.Nulls = append(dr.Nulls, k)
+			continue
+		}
+		dr.Values[k] = v
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&dr); err != nil {
+		return fmt.Errorf("failed to encode bulk query row: %w", err)
+	}
+
+	_, err := d.db.ExecContext(ctx, "INSERT INTO rows (resource_id, data) VALUES (?, ?)", resourceID, buf.Bytes())
+	return err
+}
+
+func (d *diskRowStore) Rows(ctx context.Context, resourceID string, offset int, limit int) ([]map[string]any, bool, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT data FROM rows WHERE resource_id = ? ORDER BY seq LIMIT ? OFFSET ?", resourceID, limit+1, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var ret []map[string]any
+	more := false
+	for rows.Next() {
+		if len(ret) == limit {
+			more = true
+			break
+		}
+
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, false, err
+		}
+
+		var dr diskRow
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&dr); err != nil {
+			return nil, false, fmt.Errorf("failed to decode bulk query row: %w", err)
+		}
+
+		row := dr.Values
+		if row == nil {
+			row = make(map[string]any)
+		}
+		for _, k := range dr.Nulls {
+			row[k] = nil
+		}
+		ret = append(ret, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return ret, more, nil
+}
+
+func (d *diskRowStore) Close() error {
+	return errors.Join(d.db.Close(), os.RemoveAll(d.dir))
+}
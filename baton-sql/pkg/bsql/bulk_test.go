@@ -0,0 +1,143 @@
+package bsql
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/require"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestBulkRowStore_Spill(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	store := newBulkRowStore(2, dir)
+
+	lastLogin := time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)
+	require.NoError(t, store.Append(ctx, "admin", map[string]any{"user_id": int64(1), "last_login": lastLogin}))
+	require.NoError(t, store.Append(ctx, "editor", map[string]any{"user_id": int64(2), "last_login": nil}))
+	require.Nil(t, store.disk)
+
+	require.NoError(t, store.Append(ctx, "admin", map[string]any{"user_id": int64(3), "last_login": nil}))
+	require.NotNil(t, store.disk)
+	require.Equal(t, 3, store.Len())
+
+	rows, more, err := store.Rows(ctx, "admin", 0, 1)
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Equal(t, []map[string]any{{"user_id": int64(1), "last_login": lastLogin}}, rows)
+
+	rows, more, err = store.Rows(ctx, "admin", 1, 1)
+	require.NoError(t, err)
+	require.False(t, more)
+	require.Equal(t, []map[string]any{{"user_id": int64(3), "last_login": nil}}, rows)
+
+	rows, more, err = store.Rows(ctx, "editor", 0, 10)
+	require.NoError(t, err)
+	require.False(t, more)
+	require.Equal(t, []map[string]any{{"user_id": int64(2), "last_login": nil}}, rows)
+
+	require.NoError(t, store.Close())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestBulkRowStore_DriverValues(t *testing.T) {
+	ctx := t.Context()
+	store := newBulkRowStore(1, t.TempDir())
+	defer store.Close()
+
+	id := mssql.UniqueIdentifier{0x6F, 0x96, 0x19, 0xFF, 0x8B, 0x86, 0xD0, 0x11, 0xB4, 0x2D, 0x00, 0xC0, 0x4F, 0xC9, 0x64, 0xFF}
+	idValue, err := id.Value()
+	require.NoError(t, err)
+
+	type status int16
+
+	require.NoError(t, store.Append(ctx, "admin", map[string]any{"id": id, "status": status(2), "tags": []any{int32(1), "a"}}))
+	require.NoError(t, store.Append(ctx, "admin", map[string]any{"id": nil, "status": int8(3), "tags": nil}))
+	require.NotNil(t, store.disk)
+
+	rows, _, err := store.Rows(ctx, "admin", 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{
+		{"id": idValue, "status": int64(2), "tags": []any{int64(1), "a"}},
+		{"id": nil, "status": int64(3), "tags": nil},
+	}, rows)
+
+	require.ErrorContains(t, store.Append(ctx, "admin", map[string]any{"ch": make(chan int)}), "column ch: unsupported bulk query value type chan int")
+}
+
+func TestSQLSyncer_BulkGrants(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE memberships (role_id TEXT, user_id TEXT)",
+		"INSERT INTO memberships VALUES ('admin', 'alice'), ('admin', 'bob'), ('editor', 'carol')",
+	)
+
+	s := newTestSyncer(t, db, "role", ResourceType{
+		Grants: []*GrantsQuery{
+			{
+				Query:      Query{Default: "SELECT role_id, user_id FROM memberships ORDER BY user_id"},
+				Bulk:       true,
+				ResourceID: ".role_id",
+				Map: []*GrantMapping{
+					{
+						PrincipalId:   ".user_id",
+						PrincipalType: "user",
+						Entitlement:   "member",
+					},
+				},
+			},
+		},
+	}, Config{
+		ResourceTypes: map[string]ResourceType{"role": {}, "user": {}},
+	})
+	defer s.Close()
+
+	role := func(id string) *v2.Resource {
+		return &v2.Resource{Id: &v2.ResourceId{ResourceType: "role", Resource: id}}
+	}
+
+	principals := func(grants []*v2.Grant) []string {
+		var ret []string
+		for _, g := range grants {
+			ret = append(ret, g.GetPrincipal().GetId().GetResource())
+		}
+		return ret
+	}
+
+	grants, npt, _, err := s.Grants(ctx, role("admin"), &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, npt)
+	require.Equal(t, []string{"alice", "bob"}, principals(grants))
+
+	// Rows added after the bulk query ran are not visible to the rest of the sync.
+	_, err = db.ExecContext(ctx, "INSERT INTO memberships VALUES ('editor', 'dave')")
+	require.NoError(t, err)
+
+	grants, _, _, err = s.Grants(ctx, role("editor"), &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"carol"}, principals(grants))
+
+	grants, _, _, err = s.Grants(ctx, role("viewer"), &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, grants)
+
+	// A resource that is requested again, such as a retried page, gets the same rows.
+	grants, _, _, err = s.Grants(ctx, role("admin"), &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob"}, principals(grants))
+
+	// A new sync doesn't see the cached rows of the previous one, whichever resource it asks for first.
+	require.NoError(t, s.ResetBulkResults())
+
+	grants, _, _, err = s.Grants(ctx, role("editor"), &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"carol", "dave"}, principals(grants))
+}
@@ -4,11 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+const (
+	defaultQueryKey    = "default"
+	expectRowsQueryKey = "expect_rows"
+	captureQueryKey    = "capture"
+	resourceQueryKey   = "resource_query"
 )
 
 // Config represents the overall connector configuration.
@@ -24,6 +34,77 @@ type Config struct {
 
 	// ResourceTypes defines the set of resource types (e.g., user, role) configured in the connector.
 	ResourceTypes map[string]ResourceType `yaml:"resource_types" json:"resource_types"`
+
+	// Normalize controls how raw driver values in query results are converted before they are exposed to mappings.
+	Normalize *NormalizeConfig `yaml:"normalize,omitempty" json:"normalize,omitempty"`
+
+	// TypedProfiles keeps the type of the expression result for profile values, so booleans, numbers, lists and
+	// maps reach the profile as such instead of as strings like "true" or "[a b]". It is off by default because
+	// profile values have always been strings, and existing downstream policies compare them as strings, e.g.
+	// profile.is_admin == "true", which would stop matching once the value becomes a boolean.
+	TypedProfiles bool `yaml:"typed_profiles,omitempty" json:"typed_profiles,omitempty"`
+
+	// BulkCache bounds the memory used to hold the results of bulk entitlement and grant queries.
+	BulkCache *BulkCacheConfig `yaml:"bulk_cache,omitempty" json:"bulk_cache,omitempty"`
+
+	// Lookups defines named tables that mappings can read from with the lookup() CEL function.
+	Lookups map[string]*LookupConfig `yaml:"lookups,omitempty" json:"lookups,omitempty"`
+
+	// Functions defines reusable CEL expressions that any expression can call, keyed by their signature,
+	// e.g. `normLogin(s): toLower(trim(s))`.
+	Functions map[string]string `yaml:"functions,omitempty" json:"functions,omitempty"`
+}
+
+// LookupConfig defines a query whose rows are indexed by a key column and exposed to CEL expressions
+// as `lookup('name', key)`. The query runs the first time the table is used in a sync.
+type LookupConfig struct {
+	// Query is the SQL statement that returns the rows of the lookup table.
+	Query Query `yaml:"query" json:"query"`
+
+	// Pagination defines how to page through the lookup query. All pages are loaded.
+	Pagination *Pagination `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+
+	// Key is the column the rows are indexed by.
+	Key string `yaml:"key" json:"key"`
+
+	// MaxRows is the most rows the table may hold in memory. Loading fails if the query returns more.
+	// Defaults to 100000.
+	MaxRows int `yaml:"max_rows,omitempty" json:"max_rows,omitempty"`
+
+	// RefreshInterval reloads the table when it is older than this duration (e.g. "15m").
+	// By default the table is loaded once per sync.
+	RefreshInterval string `yaml:"refresh_interval,omitempty" json:"refresh_interval,omitempty"`
+}
+
+// BulkCacheConfig configures the cache that serves bulk query results to each resource.
+type BulkCacheConfig struct {
+	// MaxMemoryRows is the number of rows held in memory before results spill to a temporary SQLite file.
+	// Defaults to 50000.
+	MaxMemoryRows int `yaml:"max_memory_rows,omitempty" json:"max_memory_rows,omitempty"`
+
+	// Dir is the directory spill files are created in. Defaults to the system temporary directory.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+}
+
+// NormalizeConfig makes query results look the same regardless of the database engine.
+type NormalizeConfig struct {
+	// Values converts column values into consistent types based on the column's database type.
+	// Text returned as bytes becomes a string, integers become int64, integral decimals become int64,
+	// times become UTC timestamps, UUID columns become UUID strings, other binary becomes hex and PostgreSQL arrays
+	// become lists.
+	Values bool `yaml:"values" json:"values"`
+
+	// UUIDColumns names binary columns that hold UUIDs, such as MySQL BINARY(16) columns filled with UUID_TO_BIN().
+	// Their 16-byte values become UUID strings instead of hex. Names are matched case-insensitively.
+	UUIDColumns []string `yaml:"uuid_columns,omitempty" json:"uuid_columns,omitempty"`
+
+	// CaseInsensitiveColumns lower-cases column names in query results and column references in expressions,
+	// so `.user_id` matches a USER_ID column returned by Oracle.
+	CaseInsensitiveColumns bool `yaml:"case_insensitive_columns" json:"case_insensitive_columns"`
+
+	// rawBinary keeps binary column values as the driver returns them instead of hex-encoding them. Asset queries set
+	// it, so image data is served as bytes.
+	rawBinary bool
 }
 
 // DatabaseConfig contains settings required to connect to the database.
@@ -66,6 +147,220 @@ type ResourceType struct {
 
 	// AccountProvisioning defines the configuration for provisioning new accounts
 	AccountProvisioning *AccountProvisioning `yaml:"account_provisioning,omitempty" json:"account_provisioning,omitempty"`
+
+	// Asset defines how to fetch the icons referenced by the icon mappings of this resource type's traits.
+	Asset *AssetQuery `yaml:"asset,omitempty" json:"asset,omitempty"`
+}
+
+// AssetQuery defines how to fetch an asset, such as a user avatar or an application logo, by its ID.
+type AssetQuery struct {
+	// Query is the SQL statement that returns the row of the asset.
+	// The value of the icon mapping is available as ?<asset_id>.
+	Query Query `yaml:"query" json:"query"`
+
+	// Data maps the row to the contents of the asset, usually a BLOB column. One of Data and Path is required.
+	Data string `yaml:"data,omitempty" json:"data,omitempty"`
+
+	// Path maps the row to the path of a file with the contents of the asset, relative to Root.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Root is the directory that asset paths are resolved in. Paths cannot refer to files outside of it.
+	Root string `yaml:"root,omitempty" json:"root,omitempty"`
+
+	// ContentType maps the row to the MIME type of the asset.
+	// If it is not set or evaluates to an empty string, the content type is detected from the contents.
+	ContentType string `yaml:"content_type,omitempty" json:"content_type,omitempty"`
+}
+
+// Query is a SQL statement that can vary by database engine.
+// In YAML it is either a single string, or a map keyed by engine name (mysql, postgres, sqlserver, oracle, sqlite)
+// with an optional `default` entry that is used for any engine without its own variant.
+type Query struct {
+	// Default is the statement used when no engine-specific variant is defined.
+	Default string
+
+	// Engines maps database engine names to engine-specific statements.
+	Engines map[string]string
+}
+
+// UnmarshalYAML accepts either a plain query string or a map of engine name to query string.
+func (q *Query) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&q.Default)
+
+	case yaml.MappingNode:
+		for ii := 0; ii+1 < len(value.Content); ii += 2 {
+			name := value.Content[ii].Value
+			node := value.Content[ii+1]
+
+			if name == expectRowsQueryKey || name == captureQueryKey {
+				return fmt.Errorf("line %d: %s is only supported on provisioning queries", value.Line, name)
+			}
+
+			var query string
+			if err := node.Decode(&query); err != nil {
+				return err
+			}
+
+			if name == defaultQueryKey {
+				q.Default = query
+				continue
+			}
+
+			if _, err := database.ParseDbEngine(name); err != nil {
+				return fmt.Errorf("line %d: invalid query variant: %w", value.Line, err)
+			}
+
+			if q.Engines == nil {
+				q.Engines = make(map[string]string)
+			}
+			q.Engines[name] = query
+		}
+
+		if q.Default == "" && len(q.Engines) == 0 {
+			return fmt.Errorf("line %d: query has no SQL statement", value.Line)
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("line %d: query must be a string or a map of engine name to query", value.Line)
+	}
+}
+
+// ProvisioningQuery is a statement run by a grant, revoke or account creation. In the map form of a query it may also
+// set `expect_rows` and `capture`.
+type ProvisioningQuery struct {
+	Query
+
+	// ExpectRows is the number of rows the statement must affect, or return if it captures values. If it is not set,
+	// the statement may affect at most one row.
+	ExpectRows *RowExpectation
+
+	// Capture maps var names to CEL expressions evaluated against the row the statement returns, such as the ID
+	// generated by an insert. The vars are bound in the statements that follow.
+	Capture map[string]string
+}
+
+// UnmarshalYAML accepts the forms of Query, with `expect_rows` and `capture` next to the statements in the map form.
+func (q *ProvisioningQuery) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return value.Decode(&q.Query)
+	}
+
+	statements := *value
+	statements.Content = nil
+	for ii := 0; ii+1 < len(value.Content); ii += 2 {
+		switch value.Content[ii].Value {
+		case expectRowsQueryKey:
+			q.ExpectRows = &RowExpectation{}
+			if err := value.Content[ii+1].Decode(q.ExpectRows); err != nil {
+				return err
+			}
+			continue
+
+		case captureQueryKey:
+			if err := value.Content[ii+1].Decode(&q.Capture); err != nil {
+				return err
+			}
+			continue
+		}
+		statements.Content = append(statements.Content, value.Content[ii], value.Content[ii+1])
+	}
+
+	return statements.Decode(&q.Query)
+}
+
+// RowExpectation is the number of rows a provisioning statement is expected to affect.
+// In YAML it is either `any`, an exact count, or a map with `exact`, `min` and/or `max`.
+type RowExpectation struct {
+	// Any accepts any number of affected rows, including none.
+	Any bool `yaml:"any,omitempty" json:"any,omitempty"`
+
+	// Exact is the exact number of rows the statement must affect.
+	Exact *int64 `yaml:"exact,omitempty" json:"exact,omitempty"`
+
+	// Min is the least number of rows the statement must affect.
+	Min *int64 `yaml:"min,omitempty" json:"min,omitempty"`
+
+	// Max is the most rows the statement may affect.
+	Max *int64 `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// UnmarshalYAML accepts `any`, a row count, or a map of bounds.
+func (e *RowExpectation) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Value == "any" {
+			e.Any = true
+			return nil
+		}
+
+		var exact int64
+		if err := value.Decode(&exact); err != nil {
+			return fmt.Errorf("line %d: expect_rows must be any, a row count, or a map of exact, min and max", value.Line)
+		}
+		e.Exact = &exact
+		return nil
+
+	case yaml.MappingNode:
+		type rowExpectation RowExpectation
+		if err := value.Decode((*rowExpectation)(e)); err != nil {
+			return err
+		}
+
+		if e.Exact != nil && (e.Min != nil || e.Max != nil) {
+			return fmt.Errorf("line %d: expect_rows exact cannot be combined with min or max", value.Line)
+		}
+		if e.Min != nil && e.Max != nil && *e.Min > *e.Max {
+			return fmt.Errorf("line %d: expect_rows min is greater than max", value.Line)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("line %d: expect_rows must be any, a row count, or a map of exact, min and max", value.Line)
+	}
+}
+
+// Check returns an error describing the mismatch if rows does not meet the expectation. The verb describes what
+// the statement did with the rows, e.g. affected or returned.
+func (e *RowExpectation) Check(verb string, rows int64) error {
+	switch {
+	case e.Any:
+		return nil
+	case e.Exact != nil && rows != *e.Exact:
+		return fmt.Errorf("%s %d rows, expected exactly %d", verb, rows, *e.Exact)
+	case e.Min != nil && rows < *e.Min:
+		return fmt.Errorf("%s %d rows, expected at least %d", verb, rows, *e.Min)
+	case e.Max != nil && rows > *e.Max:
+		return fmt.Errorf("%s %d rows, expected at most %d", verb, rows, *e.Max)
+	}
+
+	return nil
+}
+
+// String returns the default statement. Engine-specific variants are selected by Config.ResolveQueries.
+func (q Query) String() string {
+	return q.Default
+}
+
+// IsEmpty reports whether no statement was configured.
+func (q Query) IsEmpty() bool {
+	return q.Default == "" && len(q.Engines) == 0
+}
+
+// Resolve returns the statement to use for the given database engine.
+func (q Query) Resolve(engine database.DbEngine) (string, error) {
+	if query, ok := q.Engines[engine.String()]; ok {
+		return query, nil
+	}
+
+	if q.Default != "" {
+		return q.Default, nil
+	}
+
+	return "", fmt.Errorf("no query variant defined for database engine %s and no default", engine)
 }
 
 // ListQuery defines the structure for configuring resource list queries.
@@ -75,7 +370,7 @@ type ListQuery struct {
 	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
 
 	// Query is the SQL statement used to fetch a list of resources.
-	Query string `yaml:"query" json:"query"`
+	Query Query `yaml:"query" json:"query"`
 
 	// Pagination defines the pagination strategy and settings for the list query.
 	Pagination *Pagination `yaml:"pagination" json:"pagination"`
@@ -98,17 +393,38 @@ type ResourceMapping struct {
 	// Traits defines specific attribute mappings for various resource subtypes (e.g., user, role).
 	Traits *Traits `yaml:"traits" json:"traits"`
 
-	// Annotations includes additional metadata such as entitlement immutability and external links.
+	// Annotations includes additional metadata such as external links.
 	Annotations *Annotations `yaml:"annotations" json:"annotations"`
 }
 
-// Annotations holds extra metadata for resource or grant mappings.
+// Annotations holds extra metadata for resource, entitlement or grant mappings. Each value is a CEL expression
+// evaluated for every row.
 type Annotations struct {
-	// EntitlementImmutable provides settings to mark an entitlement as immutable (e.g., cannot be revoked).
-	EntitlementImmutable *v2.EntitlementImmutable `yaml:"entitlement_immutable" json:"entitlement_immutable"`
+	// EntitlementImmutable marks an entitlement as immutable (e.g., cannot be granted or revoked).
+	// It is only valid on entitlement mappings; other mappings fail with an error.
+	EntitlementImmutable *ImmutableAnnotation `yaml:"entitlement_immutable,omitempty" json:"entitlement_immutable,omitempty"`
+
+	// GrantImmutable marks a grant as managed by the application itself, so it cannot be revoked.
+	// It is only valid on grant mappings; other mappings fail with an error.
+	GrantImmutable *ImmutableAnnotation `yaml:"grant_immutable,omitempty" json:"grant_immutable,omitempty"`
 
-	// ExternalLink provides an external URL reference related to the resource or entitlement.
-	ExternalLink *v2.ExternalLink `yaml:"external_link" json:"external_link"`
+	// ExternalLink provides an external URL reference related to the resource, entitlement or grant.
+	ExternalLink *ExternalLinkAnnotation `yaml:"external_link,omitempty" json:"external_link,omitempty"`
+}
+
+// ImmutableAnnotation defines when an entitlement or grant is immutable.
+type ImmutableAnnotation struct {
+	// If is a CEL expression that decides per row whether the annotation is added. It is always added when empty.
+	If string `yaml:"if,omitempty" json:"if,omitempty"`
+
+	// SourceId is a CEL expression for the ID of the source that manages the entitlement or grant.
+	SourceId string `yaml:"source_id,omitempty" json:"source_id,omitempty"`
+}
+
+// ExternalLinkAnnotation defines a link to the object in the application, such as an admin console page.
+type ExternalLinkAnnotation struct {
+	// Url is a CEL expression for the link. No annotation is added when it evaluates to an empty string.
+	Url string `yaml:"url" json:"url"`
 }
 
 // Traits defines attribute mappings for different resource types.
@@ -124,6 +440,9 @@ type Traits struct {
 
 	// User contains trait mappings for user resources.
 	User *UserTraitMapping `yaml:"user" json:"user"`
+
+	// Secret contains trait mappings for secret resources, such as API keys and service tokens.
+	Secret *SecretTraitMapping `yaml:"secret,omitempty" json:"secret,omitempty"`
 }
 
 // UserTraitMapping defines attribute mappings specifically for user resources.
@@ -142,8 +461,13 @@ type UserTraitMapping struct {
 	// StatusDetails provides additional information about the user's status.
 	StatusDetails string `yaml:"status_details" json:"status_details"`
 
-	// Profile is a set of key-value pairs representing user profile attributes.
-	Profile map[string]string `yaml:"profile" json:"profile"`
+	// Profile is a set of key-value pairs representing user profile attributes. A value may also be a nested section
+	// of further attributes. Values are strings unless Config.TypedProfiles is set.
+	Profile map[string]any `yaml:"profile" json:"profile"`
+
+	// Icon maps the row to the ID of the user's avatar, which is fetched with the asset query of the resource type.
+	// No icon is set when it evaluates to an empty string.
+	Icon string `yaml:"icon,omitempty" json:"icon,omitempty"`
 
 	// AccountType defines the type of user account.
 	// Supported values are: user, human, service, system
@@ -156,28 +480,60 @@ type UserTraitMapping struct {
 	LoginAliases []string `yaml:"login_aliases" json:"login_aliases"`
 
 	// LastLogin records the time of the user's last login.
+	// The expression may return a timestamp, a Unix timestamp, or a string in a common database time format.
 	LastLogin string `yaml:"last_login" json:"last_login"`
 
 	// EmployeeIds stores the employee identifier(s) for the user.
 	EmployeeIDs []string `yaml:"employee_ids" json:"employee_ids"`
 
-	// ManagerID is the identifier of the user's manager.
+	// ManagerID is the identifier of the user's manager. The user trait has no manager field,
+	// so it is added to the profile as manager_id.
 	ManagerID string `yaml:"manager_id" json:"manager_id"`
 
-	// ManagerEmail is the email address of the user's manager.
+	// ManagerEmail is the email address of the user's manager, added to the profile as manager_email.
 	ManagerEmail string `yaml:"manager_email" json:"manager_email"`
 
 	// MfaEnabled indicates whether multi-factor authentication is enabled for the user.
+	// The expression may return a boolean, a number, or a string such as "true" or "1".
 	MfaEnabled string `yaml:"mfa_enabled" json:"mfa_enabled"`
 
 	// SsoEnabled indicates whether single sign-on is enabled for the user.
+	// The expression may return a boolean, a number, or a string such as "true" or "1".
 	SsoEnabled string `yaml:"sso_enabled" json:"sso_enabled"`
+
+	// CreatedAt records when the user account was created.
+	// The expression may return a timestamp, a Unix timestamp, or a string in a common database time format.
+	CreatedAt string `yaml:"created_at,omitempty" json:"created_at,omitempty"`
+
+	// StructuredName maps the parts of the user's name.
+	StructuredName *StructuredNameMapping `yaml:"structured_name,omitempty" json:"structured_name,omitempty"`
+}
+
+// StructuredNameMapping defines how to build the parts of a user's name. Each field is a CEL expression.
+type StructuredNameMapping struct {
+	// GivenName is the user's given (first) name.
+	GivenName string `yaml:"given_name,omitempty" json:"given_name,omitempty"`
+
+	// FamilyName is the user's family (last) name.
+	FamilyName string `yaml:"family_name,omitempty" json:"family_name,omitempty"`
+
+	// MiddleNames lists the user's middle names. Empty values are skipped.
+	MiddleNames []string `yaml:"middle_names,omitempty" json:"middle_names,omitempty"`
+
+	// Prefix is an honorific prefix, e.g. "Dr.".
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// Suffix is an honorific or generational suffix, e.g. "Jr.".
+	Suffix string `yaml:"suffix,omitempty" json:"suffix,omitempty"`
 }
 
 // GroupTraitMapping defines attribute mappings for group resources.
 type GroupTraitMapping struct {
-	// Profile is a set of key-value pairs representing group profile attributes.
-	Profile map[string]string `yaml:"profile" json:"profile"`
+	// Profile is a set of key-value pairs representing group profile attributes. See UserTraitMapping.Profile.
+	Profile map[string]any `yaml:"profile" json:"profile"`
+
+	// Icon maps the row to the ID of the group's icon. See UserTraitMapping.Icon.
+	Icon string `yaml:"icon,omitempty" json:"icon,omitempty"`
 }
 
 // AppTraitMapping defines attribute mappings at the application level.
@@ -185,14 +541,45 @@ type AppTraitMapping struct {
 	// HelpUrl provides a link to help documentation for the application.
 	HelpUrl string `yaml:"help_url" json:"help_url"`
 
-	// Profile is a set of key-value pairs representing application profile attributes.
-	Profile map[string]string `yaml:"profile" json:"profile"`
+	// Profile is a set of key-value pairs representing application profile attributes. See UserTraitMapping.Profile.
+	Profile map[string]any `yaml:"profile" json:"profile"`
+
+	// Icon maps the row to the ID of the application's logo. See UserTraitMapping.Icon.
+	Icon string `yaml:"icon,omitempty" json:"icon,omitempty"`
 }
 
 // RoleTraitMapping defines attribute mappings for role resources.
 type RoleTraitMapping struct {
-	// Profile is a set of key-value pairs representing role-specific attributes.
-	Profile map[string]string `yaml:"profile" json:"profile"`
+	// Profile is a set of key-value pairs representing role-specific attributes. See UserTraitMapping.Profile.
+	Profile map[string]any `yaml:"profile" json:"profile"`
+}
+
+// SecretTraitMapping defines attribute mappings for secret resources, such as API keys, personal access tokens and
+// service credentials.
+type SecretTraitMapping struct {
+	// Profile is a set of key-value pairs representing secret profile attributes. See UserTraitMapping.Profile.
+	Profile map[string]any `yaml:"profile,omitempty" json:"profile,omitempty"`
+
+	// CreatedAt records when the secret was created. It accepts the same values as UserTraitMapping.LastLogin.
+	CreatedAt string `yaml:"created_at,omitempty" json:"created_at,omitempty"`
+
+	// ExpiresAt records when the secret expires.
+	ExpiresAt string `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+
+	// LastUsedAt records when the secret was last used.
+	LastUsedAt string `yaml:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+
+	// IdentityID maps the row to the ID of the identity that the secret authenticates as, such as its owner.
+	IdentityID string `yaml:"identity_id,omitempty" json:"identity_id,omitempty"`
+
+	// IdentityType is the resource type of the identity. Defaults to user.
+	IdentityType string `yaml:"identity_type,omitempty" json:"identity_type,omitempty"`
+
+	// CreatedByID maps the row to the ID of the resource that created the secret.
+	CreatedByID string `yaml:"created_by_id,omitempty" json:"created_by_id,omitempty"`
+
+	// CreatedByType is the resource type of the creator. Defaults to user.
+	CreatedByType string `yaml:"created_by_type,omitempty" json:"created_by_type,omitempty"`
 }
 
 // Pagination defines how query results should be paginated.
@@ -211,15 +598,36 @@ type EntitlementsQuery struct {
 	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
 
 	// Query is the SQL statement used to fetch dynamic entitlements.
-	Query string `yaml:"query" json:"query"`
+	Query Query `yaml:"query" json:"query"`
 
 	// Pagination defines how pagination should be handled for the entitlements query.
 	Pagination *Pagination `yaml:"pagination" json:"pagination"`
 
+	// Bulk runs the query once for all resources of this type instead of once per resource.
+	// The resource is not available to vars, and each row is assigned to a resource using ResourceID.
+	Bulk bool `yaml:"bulk,omitempty" json:"bulk,omitempty"`
+
+	// ResourceID maps each row of a bulk query to the ID of the resource it belongs to. Required when Bulk is set.
+	ResourceID string `yaml:"resource_id,omitempty" json:"resource_id,omitempty"`
+
 	// Map contains mappings that interpret query results as entitlement objects.
 	Map []*EntitlementMapping `yaml:"map" json:"map"`
 }
 
+// UnmarshalYAML rejects `resource_query`, which only bulk grants queries support, rather than ignoring it.
+func (q *EntitlementsQuery) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		for ii := 0; ii+1 < len(value.Content); ii += 2 {
+			if value.Content[ii].Value == resourceQueryKey {
+				return fmt.Errorf("line %d: entitlements do not support resource_query, only bulk grants queries do", value.Content[ii].Line)
+			}
+		}
+	}
+
+	type plain EntitlementsQuery
+	return value.Decode((*plain)(q))
+}
+
 // EntitlementMapping defines how query results are mapped to an entitlement.
 type EntitlementMapping struct {
 	// Id is the unique identifier for the entitlement.
@@ -244,6 +652,9 @@ type EntitlementMapping struct {
 	// Immutable indicates whether this entitlement is fixed and cannot be granted or revoked.
 	Immutable bool `yaml:"immutable" json:"immutable"`
 
+	// Annotations includes additional metadata such as per-row immutability and external links.
+	Annotations *Annotations `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+
 	// SkipIf provides a CEL expression that evaluates to true in order to skip processing this entitlement mapping.
 	SkipIf string `yaml:"skip_if" json:"skip_if"`
 
@@ -268,8 +679,12 @@ type EntitlementProvisioningQueries struct {
 	// NoTransaction indicates whether the provisioning queries should be executed without a transaction.
 	NoTransaction bool `yaml:"no_transaction,omitempty" json:"no_transaction,omitempty"`
 
+	// Check is an optional query that returns a row if the principal already has the entitlement. It runs before the
+	// provisioning queries, which are skipped when granting access that already exists or revoking access that does not.
+	Check *Query `yaml:"check,omitempty" json:"check,omitempty"`
+
 	// Queries is a list of SQL statements to execute for the provisioning operation.
-	Queries []string `yaml:"queries,omitempty" json:"queries,omitempty"`
+	Queries []ProvisioningQuery `yaml:"queries,omitempty" json:"queries,omitempty"`
 }
 
 // GrantsQuery defines the structure for querying existing entitlement grants.
@@ -279,15 +694,40 @@ type GrantsQuery struct {
 	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
 
 	// Query is the SQL statement used to retrieve existing entitlement grants.
-	Query string `yaml:"query" json:"query"`
+	Query Query `yaml:"query" json:"query"`
 
 	// Pagination defines how to paginate through the results of the grants query.
 	Pagination *Pagination `yaml:"pagination" json:"pagination"`
 
+	// Bulk runs the query once for all resources of this type instead of once per resource.
+	// The resource is not available to vars, and each row is assigned to a resource using ResourceID.
+	Bulk bool `yaml:"bulk,omitempty" json:"bulk,omitempty"`
+
+	// ResourceID maps each row of a bulk query to the ID of the resource it belongs to. Required when Bulk is set.
+	ResourceID string `yaml:"resource_id,omitempty" json:"resource_id,omitempty"`
+
+	// ResourceQuery optionally returns the rows of a bulk query for a single resource. Grant uses it to list the
+	// principal's grants afterwards. Without it, Grant runs the whole bulk query and keeps only the rows of the
+	// granted resource.
+	ResourceQuery *GrantsResourceQuery `yaml:"resource_query,omitempty" json:"resource_query,omitempty"`
+
 	// Map contains mappings to interpret each row of the query result as a grant.
 	Map []*GrantMapping `yaml:"map" json:"map"`
 }
 
+// GrantsResourceQuery is the query of a bulk grants query for the rows of a single resource. Its rows are mapped with
+// the grant mappings of the bulk query.
+type GrantsResourceQuery struct {
+	// Vars provides variables that can be used within the query. The resource is available, as for a query without Bulk.
+	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+
+	// Query is the SQL statement that returns the rows of the resource.
+	Query Query `yaml:"query" json:"query"`
+
+	// Pagination defines how to paginate through the results of the query. All rows are read at once when it is unset.
+	Pagination *Pagination `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+}
+
 // GrantMapping defines how query results are mapped to an entitlement grant.
 type GrantMapping struct {
 	// SkipIf provides a CEL expression to ignore this row mapping if the condition evaluates to true.
@@ -296,7 +736,10 @@ type GrantMapping struct {
 	// PrincipalId maps the SQL result column to the principal's unique identifier.
 	PrincipalId string `yaml:"principal_id" json:"principal_id"`
 
-	// PrincipalType maps the SQL result column to the type of principal (e.g., "user" or "group").
+	// PrincipalType is a CEL expression for the resource type ID of the principal, such as
+	// `.member_kind == 'G' ? 'group' : 'user'` for queries that return principals of several types. A configured
+	// resource type ID, e.g. "user" or "service-account", is used as it is. Rows whose principal type is not a
+	// configured resource type are skipped.
 	PrincipalType string `yaml:"principal_type" json:"principal_type"`
 
 	// Entitlement maps the SQL result column to the identifier of the associated entitlement.
@@ -305,6 +748,16 @@ type GrantMapping struct {
 	// Annotations includes additional metadata for the grant mapping.
 	Annotations *Annotations `yaml:"annotations" json:"annotations"`
 
+	// Metadata maps keys to CEL expressions whose results are attached to the grant as metadata, such as when and by
+	// whom access was given. Values keep the type of the expression result, and timestamps become RFC 3339 strings.
+	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+
+	// Data maps keys to CEL expressions whose results are stored on the grant during sync, for use when the grant is
+	// revoked. Revoke vars can read them as grant.data.<key>, for example to delete the exact membership row.
+	// The values are kept in the grant's metadata under the "data" key. Integers are stored there as strings and listed
+	// under the "data_types" key, so revoke vars get them back as integers. Other numbers are doubles.
+	Data map[string]string `yaml:"data,omitempty" json:"data,omitempty"`
+
 	// Expandable indicates whether the grant should be expanded.
 	Expandable *ExpandableGrant `yaml:"expandable,omitempty" json:"expandable,omitempty"`
 }
@@ -370,7 +823,7 @@ type AccountValidationConfig struct {
 	// Vars provides variables that can be used within account validation SQL queries.
 	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
 	// Queries is a list of SQL statements to execute for account validation.
-	Query string `yaml:"query" json:"queries"`
+	Query Query `yaml:"query" json:"queries"`
 }
 
 // AccountCreationConfig defines the configuration for creating new accounts.
@@ -379,7 +832,7 @@ type AccountCreationConfig struct {
 	// Variables can reference input fields via 'input.fieldname' and credential data via 'credentials.fieldname'.
 	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
 	// Queries is a list of SQL statements to execute for account creation.
-	Queries []string `yaml:"queries" json:"queries"`
+	Queries []ProvisioningQuery `yaml:"queries" json:"queries"`
 	// NoTransaction indicates whether the creation queries should be executed without a transaction.
 	NoTransaction bool `yaml:"no_transaction,omitempty" json:"no_transaction,omitempty"`
 }
@@ -393,6 +846,111 @@ func (c Config) ExtractAccountProvisioning() (string, *AccountProvisioning, erro
 	return "", nil, ErrNoAccountProvisioningDefined
 }
 
+// ResolveQueries replaces every query in the configuration with the variant for the given database engine.
+// It returns an error naming each query that has neither a variant for the engine nor a default.
+func (c *Config) ResolveQueries(engine database.DbEngine) error {
+	var errs error
+
+	resolve := func(path string, q *Query) {
+		if q.IsEmpty() {
+			return
+		}
+
+		query, err := q.Resolve(engine)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+
+		*q = Query{Default: query}
+	}
+
+	resolveProvisioning := func(path string, p *EntitlementProvisioning) {
+		if p == nil {
+			return
+		}
+
+		if p.Grant != nil {
+			if p.Grant.Check != nil {
+				resolve(path+".grant.check", p.Grant.Check)
+			}
+			for ii := range p.Grant.Queries {
+				resolve(fmt.Sprintf("%s.grant.queries[%d]", path, ii), &p.Grant.Queries[ii].Query)
+			}
+		}
+
+		if p.Revoke != nil {
+			if p.Revoke.Check != nil {
+				resolve(path+".revoke.check", p.Revoke.Check)
+			}
+			for ii := range p.Revoke.Queries {
+				resolve(fmt.Sprintf("%s.revoke.queries[%d]", path, ii), &p.Revoke.Queries[ii].Query)
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Lookups)) {
+		if lookup := c.Lookups[name]; lookup != nil {
+			resolve("lookups."+name+".query", &lookup.Query)
+		}
+	}
+
+	for _, rtID := range slices.Sorted(maps.Keys(c.ResourceTypes)) {
+		rt := c.ResourceTypes[rtID]
+		path := "resource_types." + rtID
+
+		if rt.List != nil {
+			resolve(path+".list.query", &rt.List.Query)
+		}
+
+		for ii, e := range rt.StaticEntitlements {
+			if e == nil {
+				continue
+			}
+			resolveProvisioning(fmt.Sprintf("%s.static_entitlements[%d].provisioning", path, ii), e.Provisioning)
+		}
+
+		if rt.Entitlements != nil {
+			resolve(path+".entitlements.query", &rt.Entitlements.Query)
+
+			for ii, e := range rt.Entitlements.Map {
+				if e == nil {
+					continue
+				}
+				resolveProvisioning(fmt.Sprintf("%s.entitlements.map[%d].provisioning", path, ii), e.Provisioning)
+			}
+		}
+
+		for ii, g := range rt.Grants {
+			if g == nil {
+				continue
+			}
+			resolve(fmt.Sprintf("%s.grants[%d].query", path, ii), &g.Query)
+			if g.ResourceQuery != nil {
+				resolve(fmt.Sprintf("%s.grants[%d].resource_query.query", path, ii), &g.ResourceQuery.Query)
+			}
+		}
+
+		if rt.AccountProvisioning != nil {
+			if rt.AccountProvisioning.Create != nil {
+				for ii := range rt.AccountProvisioning.Create.Queries {
+					resolve(fmt.Sprintf("%s.account_provisioning.create.queries[%d]", path, ii), &rt.AccountProvisioning.Create.Queries[ii].Query)
+				}
+			}
+
+			if rt.AccountProvisioning.Validate != nil {
+				resolve(path+".account_provisioning.validate.query", &rt.AccountProvisioning.Validate.Query)
+			}
+		}
+
+		if rt.Asset != nil {
+			resolve(path+".asset.query", &rt.Asset.Query)
+		}
+	}
+
+	return errs
+}
+
 // Parse converts YAML-encoded configuration data into a Config struct.
 func Parse(data []byte) (*Config, error) {
 	config := &Config{}
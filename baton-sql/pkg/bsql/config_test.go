@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/database"
 )
 
 func loadExampleConfig(t *testing.T, exampleName string) string {
@@ -47,7 +49,7 @@ func TestParse(t *testing.T) {
           u.user_registered AS created_at
         FROM wp_users u
 		ORDER BY user_id ASC
-        LIMIT ?<Limit> OFFSET ?<Offset>`), normalizeQueryString(userResourceType.List.Query))
+        LIMIT ?<Limit> OFFSET ?<Offset>`), normalizeQueryString(userResourceType.List.Query.String()))
 				require.Equal(t, ".user_id", userResourceType.List.Map.Id)
 				require.Equal(t, ".username", userResourceType.List.Map.DisplayName)
 				require.Equal(t, ".email", userResourceType.List.Map.Description)
@@ -106,7 +108,7 @@ func TestParse(t *testing.T) {
 				require.Equal(t, normalizeQueryString(`
 					INSERT INTO wp_users (user_login, user_email, user_pass)
 					VALUES (?<username>, ?<email>, MD5(?<password>))
-				`), normalizeQueryString(userResourceType.AccountProvisioning.Create.Queries[0]))
+				`), normalizeQueryString(userResourceType.AccountProvisioning.Create.Queries[0].String()))
 
 				// Validate `role` resource type
 				roleResourceType := c.ResourceTypes["role"]
@@ -123,7 +125,7 @@ func TestParse(t *testing.T) {
 			um.umeta_id > ?<Cursor>
 		ORDER BY row_id ASC
 		LIMIT ?<Limit>
-`), normalizeQueryString(roleResourceType.List.Query))
+`), normalizeQueryString(roleResourceType.List.Query.String()))
 				require.Equal(t, "phpDeserializeStringArray(string(.role_name))[0]", roleResourceType.List.Map.Id)
 				require.Equal(t, "titleCase(phpDeserializeStringArray(string(.role_name))[0])", roleResourceType.List.Map.DisplayName)
 				require.Equal(t, "'Wordpress role for user'", roleResourceType.List.Map.Description)
@@ -163,3 +165,93 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_EntitlementsResourceQuery(t *testing.T) {
+	_, err := Parse([]byte(`
+resource_types:
+  role:
+    entitlements:
+      bulk: true
+      resource_id: .role_id
+      query: SELECT role_id, name FROM permissions
+      resource_query: SELECT role_id, name FROM permissions WHERE role_id = ?<role_id>
+`))
+	require.ErrorContains(t, err, "line 8: entitlements do not support resource_query, only bulk grants queries do")
+
+	c, err := Parse([]byte(`
+resource_types:
+  role:
+    entitlements:
+      bulk: true
+      resource_id: .role_id
+      query: SELECT role_id, name FROM permissions
+`))
+	require.NoError(t, err)
+	require.True(t, c.ResourceTypes["role"].Entitlements.Bulk)
+	require.Equal(t, ".role_id", c.ResourceTypes["role"].Entitlements.ResourceID)
+}
+
+func TestResolveQueries(t *testing.T) {
+	input := `
+resource_types:
+  user:
+    name: User
+    list:
+      query:
+        default: SELECT id FROM users LIMIT ?<limit>
+        sqlserver: SELECT TOP (?<limit>) id FROM users
+      map:
+        id: .id
+        display_name: .id
+    static_entitlements:
+      - id: member
+        display_name: "'Member'"
+        provisioning:
+          grant:
+            queries:
+              - INSERT INTO members (user_id) VALUES (?<principal_id>)
+              - postgres: INSERT INTO audit (msg) VALUES ('granted') RETURNING id
+                mysql: INSERT INTO audit (msg) VALUES ('granted')
+                sqlserver: INSERT INTO audit (msg) OUTPUT INSERTED.id VALUES ('granted')
+                expect_rows: any
+`
+
+	tests := []struct {
+		name      string
+		engine    database.DbEngine
+		listQuery string
+		wantErr   bool
+	}{
+		{"default variant", database.MySQL, "SELECT id FROM users LIMIT ?<limit>", false},
+		{"engine variant", database.MSSQL, "SELECT TOP (?<limit>) id FROM users", false},
+		{"no applicable variant", database.Oracle, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(input))
+			require.NoError(t, err)
+
+			err = c.ResolveQueries(tt.engine)
+			if tt.wantErr {
+				require.ErrorContains(t, err, "resource_types.user.static_entitlements[0].provisioning.grant.queries[1]")
+				return
+			}
+			require.NoError(t, err)
+
+			rt := c.ResourceTypes["user"]
+			require.Equal(t, tt.listQuery, rt.List.Query.String())
+			require.Empty(t, rt.List.Query.Engines)
+
+			grantQueries := rt.StaticEntitlements[0].Provisioning.Grant.Queries
+			require.Nil(t, grantQueries[0].ExpectRows)
+			require.Equal(t, &RowExpectation{Any: true}, grantQueries[1].ExpectRows)
+		})
+	}
+
+	_, err := Parse([]byte("resource_types:\n  user:\n    list:\n      query:\n        db2: SELECT 1\n"))
+	require.ErrorContains(t, err, "unknown database engine: db2")
+
+	_, err = Parse([]byte("resource_types:\n  user:\n    list:\n      query:\n        default: \"\"\n"))
+	require.ErrorContains(t, err, "query has no SQL statement")
+}
@@ -65,6 +65,9 @@ func (s *SQLSyncer) staticEntitlements(ctx context.Context, resource *v2.Resourc
 		if e.Immutable {
 			annos.Update(&v2.EntitlementImmutable{})
 		}
+		if err := s.mapAnnotations(ctx, annotationTargetEntitlement, &annos, e.Annotations, inputs); err != nil {
+			return nil, "", nil, err
+		}
 		entitlement.Annotations = annos
 		ret = append(ret, entitlement)
 	}
@@ -79,6 +82,35 @@ func (s *SQLSyncer) dynamicEntitlements(ctx context.Context, resource *v2.Resour
 
 	var ret []*v2.Entitlement
 
+	if s.config.Entitlements.Bulk {
+		rows, npt, err := s.bulkRows(ctx, &bulkQuery{
+			key:        s.config.Entitlements,
+			query:      s.config.Entitlements.Query.String(),
+			pagination: s.config.Entitlements.Pagination,
+			vars:       s.config.Entitlements.Vars,
+			resourceID: s.config.Entitlements.ResourceID,
+		}, resource, pToken)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		for _, rowMap := range rows {
+			for _, mapping := range s.config.Entitlements.Map {
+				r, ok, err := s.mapEntitlement(ctx, resource, mapping, rowMap)
+				if err != nil {
+					return nil, "", nil, err
+				}
+
+				if ok {
+					r.Resource = resource
+					ret = append(ret, r)
+				}
+			}
+		}
+
+		return ret, npt, nil, nil
+	}
+
 	inputs := s.env.SyncInputsWithResource(nil, resource)
 
 	queryVars, err := s.prepareQueryVars(ctx, inputs, s.config.Entitlements.Vars)
@@ -86,7 +118,7 @@ func (s *SQLSyncer) dynamicEntitlements(ctx context.Context, resource *v2.Resour
 		return nil, "", nil, err
 	}
 
-	npt, err := s.runQuery(ctx, pToken, s.config.Entitlements.Query, s.config.Entitlements.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+	npt, err := s.runQuery(ctx, pToken, s.config.Entitlements.Query.String(), s.config.Entitlements.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
 		for _, mapping := range s.config.Entitlements.Map {
 			r, ok, err := s.mapEntitlement(ctx, resource, mapping, rowMap)
 			if err != nil {
@@ -191,6 +223,9 @@ func (s *SQLSyncer) mapEntitlement(ctx context.Context, resource *v2.Resource, m
 	if mappings.Immutable {
 		annos.Update(&v2.EntitlementImmutable{})
 	}
+	if err := s.mapAnnotations(ctx, annotationTargetEntitlement, &annos, mappings.Annotations, inputs); err != nil {
+		return nil, false, err
+	}
 	ret.Annotations = annos
 
 	return ret, true, nil
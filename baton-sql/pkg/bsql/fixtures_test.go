@@ -0,0 +1,47 @@
+package bsql
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+// newTestDB opens a SQLite database in a temporary directory and runs stmts on it. The database is closed when the
+// test ends.
+func newTestDB(t *testing.T, stmts ...string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "source.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, stmt := range stmts {
+		_, err := db.ExecContext(t.Context(), stmt)
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+// newTestSyncer returns a syncer for the resource type that runs its queries on db, with a default CEL environment.
+func newTestSyncer(t *testing.T, db *sql.DB, resourceTypeID string, config ResourceType, fullConfig Config) *SQLSyncer {
+	t.Helper()
+
+	env, err := bcel.NewEnv(t.Context())
+	require.NoError(t, err)
+
+	return &SQLSyncer{
+		resourceType: &v2.ResourceType{Id: resourceTypeID},
+		db:           db,
+		dbEngine:     database.SQLite,
+		env:          env,
+		config:       config,
+		fullConfig:   fullConfig,
+	}
+}
@@ -3,8 +3,13 @@ package bsql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+
 	sdkGrant "github.com/conductorone/baton-sdk/pkg/types/grant"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -12,6 +17,12 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 )
 
+// grantDataKey is the grant metadata key that holds the values of a grant mapping's data mapping.
+const grantDataKey = "data"
+
+// grantDataTypesKey is the grant metadata key that records which grant data values are integers.
+const grantDataTypesKey = "data_types"
+
 func (s *SQLSyncer) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	if len(s.config.Grants) == 0 {
 		return nil, "", nil, nil
@@ -75,6 +86,34 @@ func (s *SQLSyncer) listGrants(ctx context.Context, resource *v2.Resource, pToke
 
 	var ret []*v2.Grant
 
+	// skipped counts the rows that were not mapped to a grant because the principal type is unknown.
+	skipped := make(map[string]int)
+
+	if grantConfig.Bulk {
+		rows, npt, err := s.bulkRows(ctx, &bulkQuery{
+			key:        grantConfig,
+			query:      grantConfig.Query.String(),
+			pagination: grantConfig.Pagination,
+			vars:       grantConfig.Vars,
+			resourceID: grantConfig.ResourceID,
+		}, resource, pToken)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, rowMap := range rows {
+			grants, err := s.mapGrants(ctx, resource, grantConfig.Map, rowMap, skipped)
+			if err != nil {
+				return nil, "", err
+			}
+			ret = append(ret, grants...)
+		}
+
+		logSkippedGrants(ctx, resource, skipped)
+
+		return ret, npt, nil
+	}
+
 	inputs := s.env.SyncInputsWithResource(nil, resource)
 
 	queryVars, err := s.prepareQueryVars(ctx, inputs, grantConfig.Vars)
@@ -82,26 +121,68 @@ func (s *SQLSyncer) listGrants(ctx context.Context, resource *v2.Resource, pToke
 		return nil, "", err
 	}
 
-	npt, err := s.runQuery(ctx, pToken, grantConfig.Query, grantConfig.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
-		for _, mapping := range grantConfig.Map {
-			g, ok, err := s.mapGrant(ctx, resource, mapping, rowMap)
-			if err != nil {
-				return false, err
-			}
-
-			if ok {
-				ret = append(ret, g)
-			}
+	npt, err := s.runQuery(ctx, pToken, grantConfig.Query.String(), grantConfig.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+		grants, err := s.mapGrants(ctx, resource, grantConfig.Map, rowMap, skipped)
+		if err != nil {
+			return false, err
 		}
+		ret = append(ret, grants...)
 		return true, nil
 	})
 	if err != nil {
 		return nil, "", err
 	}
 
+	logSkippedGrants(ctx, resource, skipped)
+
 	return ret, npt, nil
 }
 
+// mapGrants maps a row with each grant mapping. Rows with a principal type that is not a configured resource type are
+// counted in skipped instead of producing grants for principals that are never synced.
+func (s *SQLSyncer) mapGrants(ctx context.Context, resource *v2.Resource, mappings []*GrantMapping, rowMap map[string]any, skipped map[string]int) ([]*v2.Grant, error) {
+	var ret []*v2.Grant
+	for _, mapping := range mappings {
+		g, ok, err := s.mapGrant(ctx, resource, mapping, rowMap)
+		if err != nil {
+			var typeErr *unknownPrincipalTypeError
+			if errors.As(err, &typeErr) {
+				skipped[typeErr.principalType]++
+				continue
+			}
+			return nil, err
+		}
+
+		if ok {
+			ret = append(ret, g)
+		}
+	}
+
+	return ret, nil
+}
+
+func logSkippedGrants(ctx context.Context, resource *v2.Resource, skipped map[string]int) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	ctxzap.Extract(ctx).Warn(
+		"skipped grants with unknown principal types",
+		zap.String("resource_type", resource.GetId().GetResourceType()),
+		zap.String("resource_id", resource.GetId().GetResource()),
+		zap.Any("counts", skipped),
+	)
+}
+
+// unknownPrincipalTypeError is returned by mapGrant when the principal type is not a configured resource type.
+type unknownPrincipalTypeError struct {
+	principalType string
+}
+
+func (e *unknownPrincipalTypeError) Error() string {
+	return fmt.Sprintf("unknown principal type %q", e.principalType)
+}
+
 func (s *SQLSyncer) mapGrant(ctx context.Context, resource *v2.Resource, mapping *GrantMapping, rowMap map[string]any) (*v2.Grant, bool, error) {
 	if mapping == nil {
 		return nil, false, errors.New("error: missing grant mapping")
@@ -137,7 +218,19 @@ func (s *SQLSyncer) mapGrant(ctx context.Context, resource *v2.Resource, mapping
 		return nil, false, err
 	}
 
+	// A configured resource type ID is used as it is, so IDs that are not valid CEL, such as service-account, work
+	// without quoting.
 	principalType := mapping.PrincipalType
+	if _, ok := s.fullConfig.ResourceTypes[principalType]; !ok {
+		principalType, err = s.env.EvaluateString(ctx, mapping.PrincipalType, inputs)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if _, ok := s.fullConfig.ResourceTypes[principalType]; !ok {
+		return nil, false, &unknownPrincipalTypeError{principalType: principalType}
+	}
 
 	principal := &v2.ResourceId{
 		ResourceType: principalType,
@@ -176,5 +269,106 @@ func (s *SQLSyncer) mapGrant(ctx context.Context, resource *v2.Resource, mapping
 		}
 	}
 
-	return sdkGrant.NewGrant(resource, entitlementID, principal, grantOptions...), true, nil
+	if len(mapping.Metadata) > 0 || len(mapping.Data) > 0 {
+		metadata, err := s.mapGrantMetadata(ctx, mapping.Metadata, inputs)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(mapping.Data) > 0 {
+			for _, key := range []string{grantDataKey, grantDataTypesKey} {
+				if _, ok := metadata.Fields[key]; ok {
+					return nil, false, fmt.Errorf("grant metadata key %s is reserved for grant data", key)
+				}
+			}
+
+			data, dataTypes, err := s.mapGrantData(ctx, mapping.Data, inputs)
+			if err != nil {
+				return nil, false, err
+			}
+			metadata.Fields[grantDataKey] = structpb.NewStructValue(data)
+			if len(dataTypes.GetFields()) > 0 {
+				metadata.Fields[grantDataTypesKey] = structpb.NewStructValue(dataTypes)
+			}
+		}
+
+		grantOptions = append(grantOptions, sdkGrant.WithAnnotation(&v2.GrantMetadata{Metadata: metadata}))
+	}
+
+	g := sdkGrant.NewGrant(resource, entitlementID, principal, grantOptions...)
+
+	annos := annotations.Annotations(g.Annotations)
+	if err := s.mapAnnotations(ctx, annotationTargetGrant, &annos, mapping.Annotations, inputs); err != nil {
+		return nil, false, err
+	}
+	g.Annotations = annos
+
+	return g, true, nil
+}
+
+// grantData returns the data stored on a grant by the data mapping of its grant mapping, or an empty map if there is
+// none. Integers are returned as int64 or uint64 and other numbers as float64, as they were mapped.
+func grantData(grant *v2.Grant) (map[string]any, error) {
+	ret := make(map[string]any)
+
+	annos := annotations.Annotations(grant.GetAnnotations())
+	md := &v2.GrantMetadata{}
+	ok, err := annos.Pick(md)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return ret, nil
+	}
+
+	fields := md.GetMetadata().GetFields()
+	dataTypes := fields[grantDataTypesKey].GetStructValue().GetFields()
+	for k, v := range fields[grantDataKey].GetStructValue().GetFields() {
+		switch dataTypes[k].GetStringValue() {
+		case "int":
+			ret[k], err = strconv.ParseInt(v.GetStringValue(), 10, 64)
+		case "uint":
+			ret[k], err = strconv.ParseUint(v.GetStringValue(), 10, 64)
+		default:
+			ret[k] = v.AsInterface()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("grant data %s: %w", k, err)
+		}
+	}
+
+	return ret, nil
+}
+
+// mapGrantData maps the data mapping of a grant. Integers are stored as decimal strings, so they are kept exactly,
+// and their keys are recorded in the returned types.
+func (s *SQLSyncer) mapGrantData(ctx context.Context, mappings map[string]string, inputs map[string]any) (*structpb.Struct, *structpb.Struct, error) {
+	data := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(mappings))}
+	dataTypes := &structpb.Struct{Fields: make(map[string]*structpb.Value)}
+	for key, mapping := range mappings {
+		v, typeName, err := s.env.EvaluateExactValue(ctx, mapping, inputs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("grant data %s: %w", key, err)
+		}
+		data.Fields[key] = v
+
+		if typeName == "int" || typeName == "uint" {
+			dataTypes.Fields[key] = structpb.NewStringValue(typeName)
+		}
+	}
+
+	return data, dataTypes, nil
+}
+
+func (s *SQLSyncer) mapGrantMetadata(ctx context.Context, mappings map[string]string, inputs map[string]any) (*structpb.Struct, error) {
+	ret := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(mappings))}
+	for key, mapping := range mappings {
+		v, err := s.env.EvaluateValue(ctx, mapping, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("grant metadata %s: %w", key, err)
+		}
+		ret.Fields[key] = v
+	}
+
+	return ret, nil
 }
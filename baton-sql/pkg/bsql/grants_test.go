@@ -0,0 +1,323 @@
+package bsql
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+)
+
+func TestSQLSyncer_mapGrants(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	s := &SQLSyncer{
+		resourceType: &v2.ResourceType{Id: "group"},
+		env:          env,
+		fullConfig: Config{
+			ResourceTypes: map[string]ResourceType{"user": {}, "group": {}, "service-account": {}},
+		},
+	}
+
+	mappings := []*GrantMapping{
+		{
+			PrincipalId:   ".member_id",
+			PrincipalType: ".member_kind == 'G' ? 'group' : .member_kind == 'U' ? 'user' : .member_kind",
+			Entitlement:   "member",
+		},
+	}
+
+	group := &v2.Resource{Id: &v2.ResourceId{ResourceType: "group", Resource: "eng"}}
+	skipped := make(map[string]int)
+
+	var principals []*v2.ResourceId
+	for _, row := range []map[string]any{
+		{"member_id": "alice", "member_kind": "U"},
+		{"member_id": "ops", "member_kind": "G"},
+		{"member_id": "printer", "member_kind": "device"},
+		{"member_id": "scanner", "member_kind": "device"},
+	} {
+		grants, err := s.mapGrants(ctx, group, mappings, row, skipped)
+		require.NoError(t, err)
+		for _, g := range grants {
+			principals = append(principals, g.GetPrincipal().GetId())
+		}
+	}
+
+	require.Len(t, principals, 2)
+	require.Equal(t, "user", principals[0].GetResourceType())
+	require.Equal(t, "alice", principals[0].GetResource())
+	require.Equal(t, "group", principals[1].GetResourceType())
+	require.Equal(t, "ops", principals[1].GetResource())
+	require.Equal(t, map[string]int{"device": 2}, skipped)
+
+	// A configured resource type ID is used as it is, even when it is not valid CEL. Other bare identifiers are
+	// string literals, as in every other expression.
+	grants, err := s.mapGrants(ctx, group, []*GrantMapping{
+		{PrincipalId: ".member_id", PrincipalType: "service-account", Entitlement: "member"},
+		{PrincipalId: ".member_id", PrincipalType: "user", Entitlement: "member"},
+		{PrincipalId: ".member_id", PrincipalType: "member_kind", Entitlement: "member"},
+	}, map[string]any{"member_id": "ci", "member_kind": "U"}, skipped)
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+	require.Equal(t, "service-account", grants[0].GetPrincipal().GetId().GetResourceType())
+	require.Equal(t, "user", grants[1].GetPrincipal().GetId().GetResourceType())
+	require.Equal(t, map[string]int{"device": 2, "member_kind": 1}, skipped)
+
+	_, err = s.mapGrants(ctx, group, []*GrantMapping{
+		{PrincipalId: ".member_id", PrincipalType: "bot-account", Entitlement: "member"},
+	}, map[string]any{"member_id": "ci"}, skipped)
+	require.Error(t, err, "a type that is neither configured nor valid CEL is an error")
+}
+
+func TestSQLSyncer_mapGrant_Metadata(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	s := &SQLSyncer{
+		resourceType: &v2.ResourceType{Id: "group"},
+		env:          env,
+		fullConfig: Config{
+			ResourceTypes: map[string]ResourceType{"user": {}, "group": {}},
+		},
+	}
+
+	g, ok, err := s.mapGrant(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: "group", Resource: "eng"}}, &GrantMapping{
+		PrincipalId:   ".user_id",
+		PrincipalType: "user",
+		Entitlement:   "member",
+		Metadata: map[string]string{
+			"granted_at": ".granted_at",
+			"granted_by": ".granted_by",
+			"reason":     ".reason",
+			"temporary":  ".expires_at != null",
+		},
+	}, map[string]any{
+		"user_id":    "alice",
+		"granted_at": time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"granted_by": "bob",
+		"reason":     nil,
+		"expires_at": nil,
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	annos := annotations.Annotations(g.GetAnnotations())
+	md := &v2.GrantMetadata{}
+	ok, err = annos.Pick(md)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]any{
+		"granted_at": "2024-03-01T12:00:00Z",
+		"granted_by": "bob",
+		"reason":     nil,
+		"temporary":  false,
+	}, md.GetMetadata().AsMap())
+}
+
+func TestSQLSyncer_Revoke_GrantData(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE user_roles (user_role_id INTEGER, user_id TEXT, role_id TEXT)",
+		"INSERT INTO user_roles VALUES (41, 'alice', 'admin'), (42, 'alice', 'admin')",
+	)
+
+	s := newTestSyncer(t, db, "role", ResourceType{
+		StaticEntitlements: []*EntitlementMapping{
+			{
+				Id: "member",
+				Provisioning: &EntitlementProvisioning{
+					Vars: map[string]string{"user_role_id": "grant.data.user_role_id"},
+					Revoke: &EntitlementProvisioningQueries{
+						Queries: []ProvisioningQuery{{Query: Query{Default: "DELETE FROM user_roles WHERE user_role_id = ?<user_role_id>"}}},
+					},
+				},
+			},
+		},
+	}, Config{
+		ResourceTypes: map[string]ResourceType{"user": {}, "role": {}},
+	})
+
+	mapping := &GrantMapping{
+		PrincipalId:   ".user_id",
+		PrincipalType: "user",
+		Entitlement:   "member",
+		Metadata:      map[string]string{"granted_by": "'bob'"},
+		Data:          map[string]string{"user_role_id": ".user_role_id", "score": ".score", "big": ".big"},
+	}
+
+	g, ok, err := s.mapGrant(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: "role", Resource: "admin"}}, mapping, map[string]any{
+		"user_role_id": int64(42),
+		"user_id":      "alice",
+		"score":        2.0,
+		"big":          int64(1<<62 + 1),
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Numbers keep their type, and integers above 2^53 are kept exactly.
+	data, err := grantData(g)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"user_role_id": int64(42), "score": 2.0, "big": int64(1<<62 + 1)}, data)
+
+	_, err = s.Revoke(ctx, g)
+	require.NoError(t, err)
+
+	var remaining []int64
+	rows, err := db.QueryContext(ctx, "SELECT user_role_id FROM user_roles")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		require.NoError(t, rows.Scan(&id))
+		remaining = append(remaining, id)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []int64{41}, remaining)
+
+	// The data key is reserved when data is mapped.
+	mapping.Metadata = map[string]string{"data": "'x'"}
+	_, _, err = s.mapGrant(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: "role", Resource: "admin"}}, mapping, map[string]any{
+		"user_role_id": int64(42),
+		"user_id":      "alice",
+	})
+	require.ErrorContains(t, err, "reserved for grant data")
+
+	mapping.Metadata = map[string]string{"data_types": "'x'"}
+	_, _, err = s.mapGrant(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: "role", Resource: "admin"}}, mapping, map[string]any{
+		"user_role_id": int64(42),
+		"user_id":      "alice",
+	})
+	require.ErrorContains(t, err, "grant metadata key data_types is reserved for grant data")
+}
+
+func TestSQLSyncer_Grant_Check(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t, "CREATE TABLE user_roles (user_id TEXT, role_id TEXT)")
+
+	check := &Query{Default: "SELECT 1 FROM user_roles WHERE user_id = ?<user_id> AND role_id = ?<role_id>"}
+	s := newTestSyncer(t, db, "role", ResourceType{
+		StaticEntitlements: []*EntitlementMapping{
+			{
+				Id: "member",
+				Provisioning: &EntitlementProvisioning{
+					Vars: map[string]string{"user_id": "principal.ID", "role_id": "resource.ID"},
+					Grant: &EntitlementProvisioningQueries{
+						Check:   check,
+						Queries: []ProvisioningQuery{{Query: Query{Default: "INSERT INTO user_roles VALUES (?<user_id>, ?<role_id>)"}}},
+					},
+					Revoke: &EntitlementProvisioningQueries{
+						Check:   check,
+						Queries: []ProvisioningQuery{{Query: Query{Default: "DELETE FROM user_roles WHERE user_id = ?<user_id> AND role_id = ?<role_id>"}}},
+					},
+				},
+			},
+		},
+		Grants: []*GrantsQuery{
+			{
+				Query: Query{Default: "SELECT user_id FROM user_roles WHERE role_id = ?<role_id>"},
+				Vars:  map[string]string{"role_id": "resource.ID"},
+				Map: []*GrantMapping{
+					{PrincipalId: ".user_id", PrincipalType: "user", Entitlement: "member"},
+				},
+			},
+		},
+	}, Config{
+		ResourceTypes: map[string]ResourceType{"user": {}, "role": {}},
+	})
+
+	role := &v2.Resource{Id: &v2.ResourceId{ResourceType: "role", Resource: "admin"}}
+	entitlement := &v2.Entitlement{Id: "role:admin:member", Resource: role}
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: "user", Resource: "alice"}}
+
+	countRows := func() int {
+		var n int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_roles").Scan(&n))
+		return n
+	}
+
+	grants, annos, err := s.Grant(ctx, principal, entitlement)
+	require.NoError(t, err)
+	require.False(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	require.Len(t, grants, 1)
+	require.Equal(t, "role:admin:member", grants[0].GetEntitlement().GetId())
+	require.Equal(t, "alice", grants[0].GetPrincipal().GetId().GetResource())
+
+	// Retrying the grant doesn't insert the row again, and still returns the grant.
+	grants, annos, err = s.Grant(ctx, principal, entitlement)
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	require.Len(t, grants, 1)
+	require.Equal(t, 1, countRows())
+
+	annos, err = s.Revoke(ctx, grants[0])
+	require.NoError(t, err)
+	require.False(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	require.Equal(t, 0, countRows())
+
+	annos, err = s.Revoke(ctx, grants[0])
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+}
+
+func TestSQLSyncer_Grant_BulkResourceQuery(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t, "CREATE TABLE user_roles (user_id TEXT, role_id TEXT)")
+
+	s := newTestSyncer(t, db, "role", ResourceType{
+		StaticEntitlements: []*EntitlementMapping{
+			{
+				Id: "member",
+				Provisioning: &EntitlementProvisioning{
+					Vars: map[string]string{"user_id": "principal.ID", "role_id": "resource.ID"},
+					Grant: &EntitlementProvisioningQueries{
+						Queries: []ProvisioningQuery{{Query: Query{Default: "INSERT INTO user_roles VALUES (?<user_id>, ?<role_id>)"}}},
+					},
+				},
+			},
+		},
+		Grants: []*GrantsQuery{
+			{
+				// The bulk query fails if it runs, so the grants must come from the resource query.
+				Query:      Query{Default: "SELECT user_id, role_id FROM missing_table"},
+				Bulk:       true,
+				ResourceID: ".role_id",
+				ResourceQuery: &GrantsResourceQuery{
+					Vars:  map[string]string{"role_id": "resource.ID"},
+					Query: Query{Default: "SELECT user_id, role_id FROM user_roles WHERE role_id = ?<role_id>"},
+				},
+				Map: []*GrantMapping{
+					{PrincipalId: ".user_id", PrincipalType: "user", Entitlement: "member"},
+				},
+			},
+		},
+	}, Config{
+		ResourceTypes: map[string]ResourceType{"user": {}, "role": {}},
+	})
+	defer s.Close()
+
+	role := &v2.Resource{Id: &v2.ResourceId{ResourceType: "role", Resource: "admin"}}
+	entitlement := &v2.Entitlement{Id: "role:admin:member", Resource: role}
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: "user", Resource: "alice"}}
+
+	grants, _, err := s.Grant(ctx, principal, entitlement)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "alice", grants[0].GetPrincipal().GetId().GetResource())
+
+	// Without a resource query the bulk query is run.
+	s.config.Grants[0].ResourceQuery = nil
+	_, _, err = s.Grant(ctx, principal, entitlement)
+	require.ErrorContains(t, err, "missing_table")
+}
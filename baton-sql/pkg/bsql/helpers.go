@@ -2,10 +2,13 @@ package bsql
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"google.golang.org/protobuf/types/known/structpb"
+
 	"github.com/conductorone/baton-sql/pkg/database"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -138,6 +141,31 @@ func parseTimeWithEngine(value string, dbEngine database.DbEngine) (*time.Time,
 	return parseTime(value)
 }
 
+// timeValue converts the result of a time mapping into a time. Timestamps are used as is, numbers are treated as
+// Unix timestamps and strings are parsed with the formats of the database engine. Null and empty values yield nil.
+func timeValue(val any, dbEngine database.DbEngine) (*time.Time, error) {
+	switch v := val.(type) {
+	case nil, structpb.NullValue:
+		return nil, nil
+	case time.Time:
+		return &v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		return parseTimeWithEngine(v, dbEngine)
+	case []byte:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		return parseTimeWithEngine(string(v), dbEngine)
+	case int64, int32, int, uint64, uint32, uint:
+		return parseTime(fmt.Sprintf("%d", v))
+	default:
+		return nil, fmt.Errorf("expected a timestamp or string, got %T", val)
+	}
+}
+
 // generateCredentials generates a random password based on the credential options and configuration.
 func generateCredentials(credentialOptions *v2.CredentialOptions) (string, error) {
 	if credentialOptions == nil || credentialOptions.GetRandomPassword() == nil {
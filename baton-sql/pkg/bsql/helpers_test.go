@@ -7,6 +7,7 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sql/pkg/database"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func TestParseTime(t *testing.T) {
@@ -164,6 +165,45 @@ func TestParseTimeWithEngine(t *testing.T) {
 	}
 }
 
+func TestTimeValue(t *testing.T) {
+	lastLogin := time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    any
+		dbEngine database.DbEngine
+		want     *time.Time
+		wantErr  bool
+	}{
+		{"Timestamp", lastLogin, database.PostgreSQL, &lastLogin, false},
+		{"MySQL string", "2025-04-17 14:30:45", database.MySQL, &lastLogin, false},
+		{"Text bytes", []byte("2025-04-17T14:30:45Z"), database.MySQL, &lastLogin, false},
+		{"Unix seconds", int64(1744900245), database.SQLite, &lastLogin, false},
+		{"Null", structpb.NullValue_NULL_VALUE, database.MySQL, nil, false},
+		{"Nil", nil, database.MySQL, nil, false},
+		{"Empty string", "  ", database.MySQL, nil, false},
+		{"Unparseable string", "yesterday", database.MySQL, nil, true},
+		{"Unsupported type", true, database.MySQL, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := timeValue(tt.input, tt.dbEngine)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				require.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			require.True(t, tt.want.Equal(*got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
+
 func TestGenerateCredentials(t *testing.T) {
 	tests := []struct {
 		name              string
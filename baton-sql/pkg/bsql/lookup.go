@@ -0,0 +1,223 @@
+package bsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+const defaultLookupMaxRows = 100000
+
+// LookupTables loads the configured lookup tables on first use and serves their rows to the lookup() CEL function.
+// Tables are kept until Reset is called, which the connector does at the start of every sync, or until they are older
+// than their refresh interval.
+type LookupTables struct {
+	syncer   *SQLSyncer
+	configs  map[string]*LookupConfig
+	refresh  map[string]time.Duration
+	keyFolds bool
+
+	mtx    sync.RWMutex
+	tables map[string]*lookupTable
+}
+
+type lookupTable struct {
+	rows     map[string]map[string]any
+	loadedAt time.Time
+}
+
+// NewLookupTables validates the lookup configuration. No queries are run until a table is used.
+func NewLookupTables(c *Config, db *sql.DB, dbEngine database.DbEngine) (*LookupTables, error) {
+	ret := &LookupTables{
+		syncer: &SQLSyncer{
+			db:         db,
+			dbEngine:   dbEngine,
+			fullConfig: *c,
+		},
+		configs:  make(map[string]*LookupConfig),
+		refresh:  make(map[string]time.Duration),
+		keyFolds: c.Normalize != nil && c.Normalize.CaseInsensitiveColumns,
+		tables:   make(map[string]*lookupTable),
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Lookups)) {
+		lc := c.Lookups[name]
+		if lc == nil {
+			continue
+		}
+
+		if lc.Query.IsEmpty() {
+			return nil, fmt.Errorf("lookups.%s: query is required", name)
+		}
+
+		if lc.Key == "" {
+			return nil, fmt.Errorf("lookups.%s: key is required", name)
+		}
+
+		if lc.RefreshInterval != "" {
+			interval, err := time.ParseDuration(lc.RefreshInterval)
+			if err != nil {
+				return nil, fmt.Errorf("lookups.%s: invalid refresh_interval: %w", name, err)
+			}
+			ret.refresh[name] = interval
+		}
+
+		ret.configs[name] = lc
+	}
+
+	return ret, nil
+}
+
+// Load runs the query for the named table unless it is already loaded and within its refresh interval.
+func (l *LookupTables) Load(ctx context.Context, name string) error {
+	lc, ok := l.configs[name]
+	if !ok {
+		return fmt.Errorf("unknown lookup table %s", name)
+	}
+
+	l.mtx.RLock()
+	table := l.tables[name]
+	l.mtx.RUnlock()
+	if table != nil && !l.expired(name, table) {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// Another caller may have loaded the table while we waited for the lock.
+	table = l.tables[name]
+	if table != nil && !l.expired(name, table) {
+		return nil
+	}
+
+	table, err := l.load(ctx, name, lc)
+	if err != nil {
+		return fmt.Errorf("failed to load lookup table %s: %w", name, err)
+	}
+	l.tables[name] = table
+
+	return nil
+}
+
+// Lookup returns the row of a loaded table with the given key, or nil if the table has no such row.
+func (l *LookupTables) Lookup(name string, key any) (map[string]any, error) {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	table, ok := l.tables[name]
+	if !ok {
+		if _, ok := l.configs[name]; !ok {
+			return nil, fmt.Errorf("unknown lookup table %s", name)
+		}
+		return nil, fmt.Errorf("lookup table %s is not loaded: the table name must be a string literal", name)
+	}
+
+	k, ok := lookupKey(key)
+	if !ok {
+		return nil, nil
+	}
+
+	return table.rows[k], nil
+}
+
+// Reset drops every loaded table, so each is loaded again the next time it is used.
+func (l *LookupTables) Reset() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.tables = make(map[string]*lookupTable)
+}
+
+func (l *LookupTables) expired(name string, table *lookupTable) bool {
+	interval, ok := l.refresh[name]
+	return ok && time.Since(table.loadedAt) >= interval
+}
+
+func (l *LookupTables) load(ctx context.Context, name string, lc *LookupConfig) (*lookupTable, error) {
+	logger := ctxzap.Extract(ctx)
+
+	maxRows := defaultLookupMaxRows
+	if lc.MaxRows > 0 {
+		maxRows = lc.MaxRows
+	}
+
+	keyColumn := lc.Key
+	if l.keyFolds {
+		keyColumn = strings.ToLower(keyColumn)
+	}
+
+	table := &lookupTable{
+		rows:     make(map[string]map[string]any),
+		loadedAt: time.Now(),
+	}
+
+	rowCount := 0
+	pToken := &pagination.Token{Size: maxPageSize}
+	for {
+		npt, err := l.syncer.runQuery(ctx, pToken, lc.Query.String(), lc.Pagination, nil, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+			rowCount++
+			if rowCount > maxRows {
+				return false, fmt.Errorf("query returned more than max_rows (%d) rows", maxRows)
+			}
+
+			keyVal, ok := rowMap[keyColumn]
+			if !ok {
+				return false, fmt.Errorf("key column %s is not in the query results", lc.Key)
+			}
+
+			k, ok := lookupKey(keyVal)
+			if !ok {
+				return true, nil
+			}
+
+			// The first row for a key wins.
+			if _, ok := table.rows[k]; !ok {
+				table.rows[k] = rowMap
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if npt == "" {
+			break
+		}
+		pToken = &pagination.Token{Size: maxPageSize, Token: npt}
+	}
+
+	logger.Debug("loaded lookup table", zap.String("name", name), zap.Int("rows", rowCount), zap.Int("keys", len(table.rows)))
+
+	return table, nil
+}
+
+// lookupKey converts a key column value, or a key passed to lookup(), into the string the table is indexed by,
+// so that an integer key matches a numeric column regardless of how the driver returns it. NULL keys never match.
+func lookupKey(val any) (string, bool) {
+	switch v := val.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case int64, int32, int, uint64, uint32, uint, float64, float32, bool:
+		return fmt.Sprint(v), true
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), true
+	default:
+		return "", false
+	}
+}
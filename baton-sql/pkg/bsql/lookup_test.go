@@ -0,0 +1,90 @@
+package bsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+func TestLookupTables(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE departments (id INTEGER, name TEXT)",
+		"INSERT INTO departments VALUES (1, 'Engineering'), (2, 'Sales')",
+	)
+
+	c := &Config{
+		Lookups: map[string]*LookupConfig{
+			"departments": {
+				Query: Query{Default: "SELECT id, name FROM departments"},
+				Key:   "id",
+			},
+			"limited": {
+				Query:   Query{Default: "SELECT id, name FROM departments"},
+				Key:     "id",
+				MaxRows: 1,
+			},
+		},
+	}
+
+	lookups, err := NewLookupTables(c, db, database.SQLite)
+	require.NoError(t, err)
+
+	env, err := bcel.NewEnv(ctx, bcel.WithLookups(lookups))
+	require.NoError(t, err)
+
+	name, err := env.EvaluateString(ctx, "lookup('departments', .dept_id).name", env.SyncInputs(map[string]any{"dept_id": int64(2)}))
+	require.NoError(t, err)
+	require.Equal(t, "Sales", name)
+
+	// String keys match integer key columns.
+	name, err = env.EvaluateString(ctx, "lookup('departments', .dept_id).name", env.SyncInputs(map[string]any{"dept_id": "1"}))
+	require.NoError(t, err)
+	require.Equal(t, "Engineering", name)
+
+	found, err := env.EvaluateBool(ctx, "has(lookup('departments', .dept_id).name)", env.SyncInputs(map[string]any{"dept_id": int64(3)}))
+	require.NoError(t, err)
+	require.False(t, found)
+
+	// Tables are kept for the rest of the sync.
+	_, err = db.ExecContext(ctx, "UPDATE departments SET name = 'Revenue' WHERE id = 2")
+	require.NoError(t, err)
+
+	name, err = env.EvaluateString(ctx, "lookup('departments', .dept_id).name", env.SyncInputs(map[string]any{"dept_id": int64(2)}))
+	require.NoError(t, err)
+	require.Equal(t, "Sales", name)
+
+	lookups.Reset()
+	name, err = env.EvaluateString(ctx, "lookup('departments', .dept_id).name", env.SyncInputs(map[string]any{"dept_id": int64(2)}))
+	require.NoError(t, err)
+	require.Equal(t, "Revenue", name)
+
+	_, err = env.EvaluateString(ctx, "lookup('limited', .dept_id).name", env.SyncInputs(map[string]any{"dept_id": int64(1)}))
+	require.ErrorContains(t, err, "more than max_rows")
+
+	_, err = env.EvaluateString(ctx, "lookup('missing', .dept_id).name", env.SyncInputs(map[string]any{"dept_id": int64(1)}))
+	require.ErrorContains(t, err, "unknown lookup table missing")
+}
+
+func TestNewLookupTables_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		lookup  *LookupConfig
+		wantErr string
+	}{
+		{"missing query", &LookupConfig{Key: "id"}, "lookups.departments: query is required"},
+		{"missing key", &LookupConfig{Query: Query{Default: "SELECT 1"}}, "lookups.departments: key is required"},
+		{"invalid refresh interval", &LookupConfig{Query: Query{Default: "SELECT 1"}, Key: "id", RefreshInterval: "soon"}, "invalid refresh_interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLookupTables(&Config{Lookups: map[string]*LookupConfig{"departments": tt.lookup}}, nil, database.SQLite)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
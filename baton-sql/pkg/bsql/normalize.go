@@ -0,0 +1,258 @@
+package bsql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+var (
+	binaryColumnTypes = map[string]bool{
+		"BINARY":     true,
+		"VARBINARY":  true,
+		"BLOB":       true,
+		"TINYBLOB":   true,
+		"MEDIUMBLOB": true,
+		"LONGBLOB":   true,
+		"BYTEA":      true,
+		"RAW":        true,
+		"LONG RAW":   true,
+		"IMAGE":      true,
+	}
+
+	uuidColumnTypes = map[string]bool{
+		"UUID":             true,
+		"UNIQUEIDENTIFIER": true,
+	}
+
+	decimalColumnTypes = map[string]bool{
+		"DECIMAL":    true,
+		"NUMERIC":    true,
+		"NUMBER":     true,
+		"MONEY":      true,
+		"SMALLMONEY": true,
+	}
+
+	timeColumnTypes = map[string]bool{
+		"DATE":           true,
+		"DATETIME":       true,
+		"DATETIME2":      true,
+		"SMALLDATETIME":  true,
+		"DATETIMEOFFSET": true,
+		"TIMESTAMP":      true,
+		"TIMESTAMPTZ":    true,
+	}
+)
+
+// normalizeValue converts a raw driver value into a consistent shape across database engines:
+//   - text returned as bytes becomes a string
+//   - all integer types become int64 and all floats become float64
+//   - decimals become int64 when integral, float64 otherwise
+//   - times become UTC time.Time values, including MySQL datetimes returned as text
+//   - UUID columns, and binary columns listed in uuid_columns, become canonical UUID strings; other binary becomes hex
+//   - PostgreSQL one-dimensional arrays become lists.
+//
+// uuidColumn reports whether the column is listed in uuid_columns.
+func normalizeValue(dbEngine database.DbEngine, dbTypeName string, uuidColumn bool, val any) any {
+	typeName := strings.ToUpper(dbTypeName)
+
+	switch v := val.(type) {
+	case nil:
+		return nil
+
+	case []byte:
+		if uuidColumnTypes[typeName] && len(v) == 16 {
+			return formatUUID(v, dbEngine == database.MSSQL)
+		}
+
+		if binaryColumnTypes[typeName] {
+			if uuidColumn && len(v) == 16 {
+				return formatUUID(v, false)
+			}
+			return hex.EncodeToString(v)
+		}
+
+		return normalizeString(dbEngine, typeName, string(v))
+
+	case [16]byte:
+		return formatUUID(v[:], false)
+
+	case string:
+		return normalizeString(dbEngine, typeName, v)
+
+	case time.Time:
+		return v.UTC()
+
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return normalizeUint(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return normalizeUint(v)
+	case float32:
+		return float64(v)
+	case float64:
+		if decimalColumnTypes[typeName] {
+			return normalizeFloat(v)
+		}
+		return v
+
+	default:
+		return val
+	}
+}
+
+func normalizeString(dbEngine database.DbEngine, typeName string, s string) any {
+	switch {
+	case decimalColumnTypes[typeName]:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return normalizeFloat(f)
+		}
+
+	case timeColumnTypes[typeName]:
+		if t, err := parseTimeWithEngine(s, dbEngine); err == nil {
+			return t.UTC()
+		}
+
+	case strings.HasPrefix(typeName, "_") && dbEngine == database.PostgreSQL:
+		if items, ok := parsePostgresArray(s); ok {
+			return normalizePostgresArray(strings.TrimPrefix(typeName, "_"), items)
+		}
+	}
+
+	return s
+}
+
+// normalizeUint keeps values that do not fit in an int64 as uint64.
+func normalizeUint(v uint64) any {
+	if v > math.MaxInt64 {
+		return v
+	}
+	return int64(v)
+}
+
+// normalizeFloat returns integral decimal values as int64, so NUMBER(10) keys match integer keys on other engines.
+func normalizeFloat(f float64) any {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f <= math.MaxInt64 {
+		return int64(f)
+	}
+	return f
+}
+
+// formatUUID formats 16 bytes as a canonical UUID string. SQL Server stores the first three groups little-endian.
+func formatUUID(b []byte, mixedEndian bool) string {
+	u := make([]byte, 16)
+	copy(u, b)
+
+	if mixedEndian {
+		u[0], u[1], u[2], u[3] = u[3], u[2], u[1], u[0]
+		u[4], u[5] = u[5], u[4]
+		u[6], u[7] = u[7], u[6]
+	}
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// parsePostgresArray parses a one-dimensional PostgreSQL array literal such as {a,"b c",NULL}.
+// It reports false for anything it cannot parse, including multi-dimensional arrays.
+func parsePostgresArray(s string) ([]any, bool) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, false
+	}
+
+	body := s[1 : len(s)-1]
+	items := make([]any, 0)
+	if body == "" {
+		return items, true
+	}
+
+	var current strings.Builder
+	quoted := false
+	wasQuoted := false
+
+	flush := func() {
+		item := current.String()
+		if !wasQuoted && strings.EqualFold(item, "NULL") {
+			items = append(items, nil)
+		} else {
+			items = append(items, item)
+		}
+		current.Reset()
+		wasQuoted = false
+	}
+
+	for ii := 0; ii < len(body); ii++ {
+		c := body[ii]
+		switch {
+		case c == '\\' && quoted:
+			ii++
+			if ii >= len(body) {
+				return nil, false
+			}
+			current.WriteByte(body[ii])
+		case c == '"':
+			quoted = !quoted
+			wasQuoted = true
+		case c == '{' && !quoted:
+			return nil, false
+		case c == ',' && !quoted:
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	if quoted {
+		return nil, false
+	}
+	flush()
+
+	return items, true
+}
+
+// normalizePostgresArray converts array elements to the normalized type of the array's element type.
+func normalizePostgresArray(elemTypeName string, items []any) []any {
+	for ii, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			continue
+		}
+
+		switch elemTypeName {
+		case "INT2", "INT4", "INT8":
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				items[ii] = i
+			}
+		case "FLOAT4", "FLOAT8":
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				items[ii] = f
+			}
+		case "NUMERIC":
+			items[ii] = normalizeString(database.PostgreSQL, elemTypeName, s)
+		case "BOOL":
+			items[ii] = s == "t" || s == "true"
+		}
+	}
+
+	return items
+}
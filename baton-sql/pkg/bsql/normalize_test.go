@@ -0,0 +1,53 @@
+package bsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbEngine database.DbEngine
+		typeName string
+		uuid     bool
+		input    any
+		expected any
+	}{
+		{"nil", database.MySQL, "VARCHAR", false, nil, nil},
+		{"MySQL text bytes", database.MySQL, "VARCHAR", false, []byte("alice"), "alice"},
+		{"MySQL integral decimal", database.MySQL, "DECIMAL", false, []byte("42"), int64(42)},
+		{"MySQL fractional decimal", database.MySQL, "DECIMAL", false, []byte("12.50"), 12.5},
+		{"MySQL datetime bytes", database.MySQL, "DATETIME", false, []byte("2025-04-17 14:30:45"), time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)},
+		{"MySQL binary UUID column", database.MySQL, "BINARY", true, []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}, "123e4567-e89b-12d3-a456-426614174000"},
+		{"MySQL 16-byte binary is hex", database.MySQL, "BINARY", false, []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}, "123e4567e89b12d3a456426614174000"},
+		{"MySQL blob", database.MySQL, "BLOB", false, []byte{0xde, 0xad, 0xbe, 0xef}, "deadbeef"},
+		{"MySQL unsigned int", database.MySQL, "INT", false, uint32(7), int64(7)},
+		{"SQL Server uniqueidentifier", database.MSSQL, "UNIQUEIDENTIFIER", false, []byte{0x67, 0x45, 0x3e, 0x12, 0x9b, 0xe8, 0xd3, 0x12, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}, "123e4567-e89b-12d3-a456-426614174000"},
+		{"Postgres UUID array", database.PostgreSQL, "UUID", false, [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}, "123e4567-e89b-12d3-a456-426614174000"},
+		{"Postgres text array", database.PostgreSQL, "_TEXT", false, `{admin,"read only",NULL,"NULL"}`, []any{"admin", "read only", nil, "NULL"}},
+		{"Postgres int array", database.PostgreSQL, "_INT4", false, "{1,2,3}", []any{int64(1), int64(2), int64(3)}},
+		{"Postgres empty array", database.PostgreSQL, "_TEXT", false, "{}", []any{}},
+		{"Postgres multi-dimensional array is left as is", database.PostgreSQL, "_INT4", false, "{{1,2},{3,4}}", "{{1,2},{3,4}}"},
+		{"Oracle integral number", database.Oracle, "NUMBER", false, float64(10), int64(10)},
+		{"Time is converted to UTC", database.PostgreSQL, "TIMESTAMPTZ", false, time.Date(2025, 4, 17, 16, 30, 45, 0, time.FixedZone("CEST", 2*60*60)), time.Date(2025, 4, 17, 14, 30, 45, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeValue(tt.dbEngine, tt.typeName, tt.uuid, tt.input)
+			if expected, ok := tt.expected.(time.Time); ok {
+				gotTime, ok := got.(time.Time)
+				require.True(t, ok, "expected time.Time, got %T", got)
+				require.True(t, expected.Equal(gotTime))
+				require.Equal(t, time.UTC, gotTime.Location())
+				return
+			}
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
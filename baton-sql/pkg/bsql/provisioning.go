@@ -7,11 +7,15 @@ import (
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sql/pkg/helpers"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 )
 
+var _ connectorbuilder.ResourceProvisionerV2 = (*SQLSyncer)(nil)
+
 // getProvisioningConfig fetches the provisioning config for the given entitlement if it exists.
 func (s *SQLSyncer) getProvisioningConfig(ctx context.Context, entitlementID string) (*EntitlementProvisioning, bool) {
 	l := ctxzap.Extract(ctx)
@@ -40,46 +44,70 @@ func (s *SQLSyncer) getProvisioningConfig(ctx context.Context, entitlementID str
 	return nil, false
 }
 
-func (s *SQLSyncer) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+// Grant runs the grant queries of the entitlement and returns the grants the principal has for it afterwards, as
+// mapped by the resource type's grants queries. If the grant check finds that the access already exists, the grant
+// queries are skipped and the GrantAlreadyExists annotation is returned.
+func (s *SQLSyncer) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
 	l.Debug("granting entitlement", zap.String("entitlement_id", entitlement.GetId()))
 
 	_, _, entitlementID, err := helpers.SplitEntitlementID(entitlement)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	provisioningConfig, ok := s.getProvisioningConfig(ctx, entitlementID)
 	if !ok {
-		return nil, errors.New("provisioning is not enabled for this connector")
+		return nil, nil, errors.New("provisioning is not enabled for this connector")
 	}
 
 	if provisioningConfig.Grant == nil {
-		return nil, errors.New("no grant config found for entitlement")
+		return nil, nil, errors.New("no grant config found for entitlement")
 	}
 
 	if len(provisioningConfig.Grant.Queries) == 0 {
-		return nil, errors.New("no grant config found for entitlement")
+		return nil, nil, errors.New("no grant config found for entitlement")
 	}
 
-	provisioningVars, err := s.prepareProvisioningVars(ctx, provisioningConfig.Vars, principal, entitlement)
+	provisioningVars, err := s.prepareProvisioningVars(ctx, provisioningConfig.Vars, principal, entitlement, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	useTx := !provisioningConfig.Grant.NoTransaction
-	err = s.runProvisioningQueries(ctx, provisioningConfig.Grant.Queries, provisioningVars, useTx)
+	var annos annotations.Annotations
+	exists, err := s.checkProvisioning(ctx, provisioningConfig.Grant.Check, provisioningVars)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	l.Debug(
-		"granted entitlement",
-		zap.String("principal_id", principal.GetId().GetResource()),
-		zap.String("entitlement_id", entitlement.GetId()),
-	)
-	return nil, nil
+	if exists {
+		l.Debug(
+			"grant already exists",
+			zap.String("principal_id", principal.GetId().GetResource()),
+			zap.String("entitlement_id", entitlement.GetId()),
+		)
+		annos.Update(&v2.GrantAlreadyExists{})
+	} else {
+		useTx := !provisioningConfig.Grant.NoTransaction
+		_, err = s.runProvisioningQueries(ctx, provisioningConfig.Grant.Queries, provisioningVars, useTx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		l.Debug(
+			"granted entitlement",
+			zap.String("principal_id", principal.GetId().GetResource()),
+			zap.String("entitlement_id", entitlement.GetId()),
+		)
+	}
+
+	grants, err := s.principalGrants(ctx, principal, entitlement)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return grants, annos, nil
 }
 
 func (s *SQLSyncer) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
@@ -108,13 +136,25 @@ func (s *SQLSyncer) Revoke(ctx context.Context, grant *v2.Grant) (annotations.An
 		return nil, errors.New("no revoke config found for entitlement")
 	}
 
-	provisioningVars, err := s.prepareProvisioningVars(ctx, provisioningConfig.Vars, grant.GetPrincipal(), grant.GetEntitlement())
+	provisioningVars, err := s.prepareProvisioningVars(ctx, provisioningConfig.Vars, grant.GetPrincipal(), grant.GetEntitlement(), grant)
 	if err != nil {
 		return nil, err
 	}
 
+	if provisioningConfig.Revoke.Check != nil {
+		exists, err := s.checkProvisioning(ctx, provisioningConfig.Revoke.Check, provisioningVars)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			l.Debug("grant already revoked", zap.String("grant_id", grant.GetId()))
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+	}
+
 	useTx := !provisioningConfig.Revoke.NoTransaction
-	err = s.runProvisioningQueries(ctx, provisioningConfig.Revoke.Queries, provisioningVars, useTx)
+	_, err = s.runProvisioningQueries(ctx, provisioningConfig.Revoke.Queries, provisioningVars, useTx)
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +163,153 @@ func (s *SQLSyncer) Revoke(ctx context.Context, grant *v2.Grant) (annotations.An
 	return nil, nil
 }
 
-func (s *SQLSyncer) prepareProvisioningVars(ctx context.Context, vars map[string]string, principal *v2.Resource, entitlement *v2.Entitlement) (map[string]any, error) {
+// checkProvisioning runs a provisioning check query and reports whether it returned a row. It reports false if there
+// is no check query.
+func (s *SQLSyncer) checkProvisioning(ctx context.Context, check *Query, vars map[string]any) (bool, error) {
+	if check == nil {
+		return false, nil
+	}
+
+	q, qArgs, err := s.prepareProvisioningQuery(check.String(), vars)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, q, qArgs...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	found := rows.Next()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// principalGrants lists the grants of the entitlement's resource with each grants query, and returns the ones of the
+// entitlement that belong to the principal.
+func (s *SQLSyncer) principalGrants(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, error) {
+	resource := entitlement.GetResource()
+	if resource.GetId() == nil {
+		resourceType, resourceID, _, err := helpers.SplitEntitlementID(entitlement)
+		if err != nil {
+			return nil, err
+		}
+		resource = &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceType, Resource: resourceID}}
+	}
+
+	var ret []*v2.Grant
+	for _, grantConfig := range s.config.Grants {
+		grants, err := s.allGrants(ctx, resource, grantConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range grants {
+			if g.GetEntitlement().GetId() != entitlement.GetId() {
+				continue
+			}
+			if g.GetPrincipal().GetId().GetResourceType() != principal.GetId().GetResourceType() ||
+				g.GetPrincipal().GetId().GetResource() != principal.GetId().GetResource() {
+				continue
+			}
+			ret = append(ret, g)
+		}
+	}
+
+	return ret, nil
+}
+
+// allGrants returns every page of a grants query for the resource. Bulk queries are not read from the bulk cache, which
+// may still hold rows from before the grant. Their resource query is run instead if there is one. Otherwise the whole
+// bulk query is run and the rows of other resources are discarded, which reads every grant of the resource type.
+func (s *SQLSyncer) allGrants(ctx context.Context, resource *v2.Resource, grantConfig *GrantsQuery) ([]*v2.Grant, error) {
+	if grantConfig == nil {
+		return nil, errors.New("error: missing grants query")
+	}
+
+	if grantConfig.Bulk && grantConfig.ResourceQuery != nil {
+		resourceConfig := *grantConfig
+		resourceConfig.Vars = grantConfig.ResourceQuery.Vars
+		resourceConfig.Query = grantConfig.ResourceQuery.Query
+		resourceConfig.Pagination = grantConfig.ResourceQuery.Pagination
+		resourceConfig.Bulk = false
+		grantConfig = &resourceConfig
+	}
+
+	if !grantConfig.Bulk {
+		var ret []*v2.Grant
+		pToken := &pagination.Token{Size: maxPageSize}
+		for {
+			grants, npt, err := s.listGrants(ctx, resource, pToken, grantConfig)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, grants...)
+
+			if npt == "" {
+				return ret, nil
+			}
+			pToken = &pagination.Token{Size: maxPageSize, Token: npt}
+		}
+	}
+
+	if grantConfig.ResourceID == "" {
+		return nil, errors.New("resource_id mapping is required for bulk queries")
+	}
+
+	queryVars, err := s.prepareQueryVars(ctx, s.env.SyncInputs(nil), grantConfig.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*v2.Grant
+	skipped := make(map[string]int)
+	pToken := &pagination.Token{Size: maxPageSize}
+	for {
+		npt, err := s.runQuery(ctx, pToken, grantConfig.Query.String(), grantConfig.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+			resourceID, err := s.env.EvaluateString(ctx, grantConfig.ResourceID, s.env.SyncInputs(rowMap))
+			if err != nil {
+				return false, err
+			}
+			if resourceID != resource.GetId().GetResource() {
+				return true, nil
+			}
+
+			grants, err := s.mapGrants(ctx, resource, grantConfig.Map, rowMap, skipped)
+			if err != nil {
+				return false, err
+			}
+			ret = append(ret, grants...)
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if npt == "" {
+			break
+		}
+		pToken = &pagination.Token{Size: maxPageSize, Token: npt}
+	}
+
+	logSkippedGrants(ctx, resource, skipped)
+
+	return ret, nil
+}
+
+// prepareProvisioningVars evaluates the provisioning vars. When revoking, grant is the grant being revoked and the
+// data stored on it during sync is available as grant.data.
+func (s *SQLSyncer) prepareProvisioningVars(
+	ctx context.Context,
+	vars map[string]string,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+	grant *v2.Grant,
+) (map[string]any, error) {
 	if principal == nil {
 		return nil, errors.New("principal is required")
 	}
@@ -139,6 +325,14 @@ func (s *SQLSyncer) prepareProvisioningVars(ctx context.Context, vars map[string
 		return nil, err
 	}
 
+	if grant != nil {
+		data, err := grantData(grant)
+		if err != nil {
+			return nil, err
+		}
+		inputs = s.env.WithGrantInputs(inputs, grant, data)
+	}
+
 	for k, v := range vars {
 		out, err := s.env.Evaluate(ctx, v, inputs)
 		if err != nil {
@@ -155,7 +349,7 @@ func (s *SQLSyncer) validateAccount(ctx context.Context, accountProvisioning *Ac
 		return nil, fmt.Errorf("validation configuration is not defined for account provisioning")
 	}
 
-	if accountProvisioning.Validate.Query == "" {
+	if accountProvisioning.Validate.Query.String() == "" {
 		return nil, fmt.Errorf("validation query is not defined for account provisioning")
 	}
 
@@ -165,7 +359,7 @@ func (s *SQLSyncer) validateAccount(ctx context.Context, accountProvisioning *Ac
 	}
 
 	var ret *v2.Resource
-	_, err = s.runQuery(ctx, nil, accountProvisioning.Validate.Query, nil, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+	_, err = s.runQuery(ctx, nil, accountProvisioning.Validate.Query.String(), nil, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
 		r, err := s.mapResource(ctx, rowMap)
 		if err != nil {
 			return false, err
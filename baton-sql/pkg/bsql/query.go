@@ -5,10 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/google/cel-go/common/types/ref"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 
@@ -17,17 +21,22 @@ import (
 )
 
 const (
-	maxPageSize     = 1000
-	minPageSize     = 1
-	defaultPageSize = 100
-	offsetKey       = "offset"
-	cursorKey       = "cursor"
-	limitKey        = "limit"
-	unquotedKey     = "unquoted"
+	maxPageSize      = 1000
+	minPageSize      = 1
+	defaultPageSize  = 100
+	offsetKey        = "offset"
+	cursorKey        = "cursor"
+	limitKey         = "limit"
+	unquotedKey      = "unquoted"
+	listKey          = "list"
+	identifierKey    = "identifier"
+	literalKey       = "literal"
+	maxIdentifierLen = 128
 )
 
 type executor interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
 type paginationContext struct {
@@ -39,11 +48,18 @@ type paginationContext struct {
 }
 
 type queryTokenOpts struct {
-	Key      string
-	Unquoted bool
+	Key        string
+	Unquoted   bool
+	List       bool
+	Identifier bool
+	Literal    bool
 }
 
-var queryOptRegex = regexp.MustCompile(`\?\<([a-zA-Z0-9_]+)(?:\|([a-zA-Z0-9_]+))?\>`)
+var queryOptRegex = regexp.MustCompile(`\?\<([a-zA-Z0-9_]+)(?:\|([a-zA-Z0-9_,]+))?\>`)
+
+// identifierRegex restricts each dot-separated part of a value interpolated with the identifier option. Quote
+// characters, whitespace and statement separators are never allowed, so a quoted identifier cannot be escaped from.
+var identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_$@-]*$`)
 
 func (s *SQLSyncer) getNextPlaceholder(qArgs []interface{}) string {
 	switch s.dbEngine {
@@ -87,14 +103,219 @@ func parseToken(token string) (*queryTokenOpts, error) {
 		switch opt {
 		case unquotedKey:
 			opts.Unquoted = true
+		case listKey:
+			opts.List = true
+		case identifierKey:
+			opts.Identifier = true
+		case literalKey:
+			opts.Literal = true
 		default:
 			return nil, fmt.Errorf("unknown option %s", opt)
 		}
 	}
 
+	exclusive := 0
+	for _, set := range []bool{opts.Unquoted, opts.List, opts.Identifier, opts.Literal} {
+		if set {
+			exclusive++
+		}
+	}
+	if exclusive > 1 {
+		return nil, fmt.Errorf("only one of the %s, %s, %s and %s options may be used", unquotedKey, listKey, identifierKey, literalKey)
+	}
+
 	return opts, nil
 }
 
+// bindToken returns the SQL that replaces a token, appending any bound values to qArgs.
+func (s *SQLSyncer) bindToken(opts *queryTokenOpts, val any, qArgs []interface{}) (string, []interface{}, error) {
+	// If the value is unquoted, directly insert the value as a string
+	if opts.Unquoted {
+		return fmt.Sprintf("%v", val), qArgs, nil
+	}
+
+	if opts.List {
+		return s.bindList(val, qArgs)
+	}
+
+	if opts.Identifier {
+		quoted, err := s.quoteIdentifier(val)
+		return quoted, qArgs, err
+	}
+
+	if opts.Literal {
+		quoted, err := s.quoteLiteral(val)
+		return quoted, qArgs, err
+	}
+
+	qArgs = append(qArgs, val)
+	return s.getNextPlaceholder(qArgs), qArgs, nil
+}
+
+// bindList binds a list value. PostgreSQL receives the list as a single array parameter, for use with `= ANY(...)`.
+// Other engines receive one placeholder per item, for use with `IN (...)`. An empty list expands to NULL,
+// so `IN (...)` matches no rows instead of producing invalid SQL. `NOT IN (NULL)` matches no rows either, because
+// comparisons with NULL are never true, so an empty exclusion list excludes everything; guard it in the query, e.g.
+// with a CASE or a separate condition. PostgreSQL binds an empty array instead, and `<> ALL(...)` with an empty array
+// matches every row, so exclusions behave differently there.
+func (s *SQLSyncer) bindList(val any, qArgs []interface{}) (string, []interface{}, error) {
+	items, err := toList(val)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if s.dbEngine == database.PostgreSQL {
+		qArgs = append(qArgs, postgresArray(items))
+		return s.getNextPlaceholder(qArgs), qArgs, nil
+	}
+
+	if len(items) == 0 {
+		return "NULL", qArgs, nil
+	}
+
+	placeholders := make([]string, 0, len(items))
+	for _, item := range items {
+		qArgs = append(qArgs, item)
+		placeholders = append(placeholders, s.getNextPlaceholder(qArgs))
+	}
+
+	return strings.Join(placeholders, ", "), qArgs, nil
+}
+
+// quoteIdentifier validates an identifier, such as a user or role name, and quotes it for the database engine.
+// A qualified name such as `schema.table` is split on dots and each part is quoted on its own. Names that contain
+// dots themselves, such as e-mail user names, need the literal option where the statement accepts a string, e.g.
+// MySQL account names.
+//
+// Quoted names are matched exactly. Oracle and PostgreSQL fold unquoted names to upper and lower case respectively,
+// so the value must use the case the name is stored in, e.g. `APP_READER` for a role created as app_reader on Oracle.
+func (s *SQLSyncer) quoteIdentifier(val any) (string, error) {
+	var ident string
+	switch v := val.(type) {
+	case string:
+		ident = v
+	case []byte:
+		ident = string(v)
+	default:
+		return "", fmt.Errorf("expected a string identifier, got %T", val)
+	}
+
+	parts := strings.Split(ident, ".")
+	quoted := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if len(part) > maxIdentifierLen {
+			return "", fmt.Errorf("identifier is longer than %d characters", maxIdentifierLen)
+		}
+
+		if !identifierRegex.MatchString(part) {
+			return "", fmt.Errorf(
+				"invalid identifier %q: each dot-separated part must start with a letter or underscore and contain only letters, digits, and _ $ @ -",
+				ident,
+			)
+		}
+
+		switch s.dbEngine {
+		case database.MySQL:
+			quoted = append(quoted, "`"+part+"`")
+		case database.MSSQL:
+			quoted = append(quoted, "["+part+"]")
+		default:
+			quoted = append(quoted, `"`+part+`"`)
+		}
+	}
+
+	return strings.Join(quoted, "."), nil
+}
+
+// quoteLiteral renders a value as an escaped SQL string literal, for statements that cannot take bind parameters.
+func (s *SQLSyncer) quoteLiteral(val any) (string, error) {
+	if val == nil {
+		return "NULL", nil
+	}
+
+	var str string
+	switch v := val.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		str = fmt.Sprintf("%v", v)
+	}
+
+	if strings.ContainsRune(str, 0) {
+		return "", errors.New("string literals cannot contain NUL characters")
+	}
+
+	str = strings.ReplaceAll(str, "'", "''")
+
+	switch s.dbEngine {
+	case database.MySQL:
+		// MySQL treats backslashes as escape characters unless NO_BACKSLASH_ESCAPES is set.
+		str = strings.ReplaceAll(str, `\`, `\\`)
+		return "'" + str + "'", nil
+	case database.MSSQL:
+		return "N'" + str + "'", nil
+	default:
+		return "'" + str + "'", nil
+	}
+}
+
+// toList converts a slice or array value, including CEL lists, into a list of native values.
+func toList(val any) ([]any, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(val)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, fmt.Errorf("expected a list value, got %T", val)
+	}
+
+	ret := make([]any, 0, rv.Len())
+	for ii := 0; ii < rv.Len(); ii++ {
+		item := rv.Index(ii).Interface()
+		if celVal, ok := item.(ref.Val); ok {
+			item = celVal.Value()
+		}
+		ret = append(ret, item)
+	}
+
+	return ret, nil
+}
+
+// postgresArray returns a typed slice when every item shares a type, so the driver can encode it as an array.
+func postgresArray(items []any) any {
+	if len(items) == 0 {
+		return []string{}
+	}
+
+	switch items[0].(type) {
+	case string:
+		ret := make([]string, 0, len(items))
+		for _, item := range items {
+			v, ok := item.(string)
+			if !ok {
+				return items
+			}
+			ret = append(ret, v)
+		}
+		return ret
+	case int64:
+		ret := make([]int64, 0, len(items))
+		for _, item := range items {
+			v, ok := item.(int64)
+			if !ok {
+				return items
+			}
+			ret = append(ret, v)
+		}
+		return ret
+	default:
+		return items
+	}
+}
+
 func (s *SQLSyncer) parseQueryOpts(pCtx *paginationContext, query string, vars map[string]any) (string, []interface{}, bool, error) {
 	if vars == nil {
 		vars = make(map[string]any)
@@ -133,13 +354,14 @@ func (s *SQLSyncer) parseQueryOpts(pCtx *paginationContext, query string, vars m
 			val = v
 		}
 
-		// If the value is unquoted, directly insert the value as a string
-		if opts.Unquoted {
-			return fmt.Sprintf("%v", val)
+		replacement, updatedArgs, err := s.bindToken(opts, val, qArgs)
+		if err != nil {
+			parseErr = errors.Join(parseErr, fmt.Errorf("in token %s: %w", token, err))
+			return token
 		}
+		qArgs = updatedArgs
 
-		qArgs = append(qArgs, val)
-		return s.getNextPlaceholder(qArgs)
+		return replacement
 	})
 	if parseErr != nil {
 		return "", nil, false, parseErr
@@ -279,12 +501,19 @@ func (s *SQLSyncer) prepareProvisioningQuery(query string, vars map[string]any)
 			return token
 		}
 
-		if opts.Unquoted {
-			return fmt.Sprintf("%v", v)
+		if mc, ok := v.(missingCapture); ok {
+			parseErr = errors.Join(parseErr, fmt.Errorf("in token %s: not captured, provisioning query %d returned no rows", token, mc.query))
+			return token
 		}
 
-		qArgs = append(qArgs, v)
-		return s.getNextPlaceholder(qArgs)
+		replacement, updatedArgs, err := s.bindToken(opts, v, qArgs)
+		if err != nil {
+			parseErr = errors.Join(parseErr, fmt.Errorf("in token %s: %w", token, err))
+			return token
+		}
+		qArgs = updatedArgs
+
+		return replacement
 	})
 	if parseErr != nil {
 		return "", nil, parseErr
@@ -292,16 +521,25 @@ func (s *SQLSyncer) prepareProvisioningQuery(query string, vars map[string]any)
 	return updatedQuery, qArgs, nil
 }
 
-func (s *SQLSyncer) runProvisioningQueries(ctx context.Context, queries []string, vars map[string]any, useTx bool) error {
+// missingCapture is bound in place of a captured var when its statement returned no rows, so that only statements
+// that reference the var fail.
+type missingCapture struct {
+	query int
+}
+
+// runProvisioningQueries executes the queries in order, in a transaction if useTx is set and on a single connection
+// otherwise. Each query must affect the number of rows it expects, and the first that does not, or fails, ends the run
+// with an error naming its index. Values captured from queries are bound in the queries after them, and are returned.
+func (s *SQLSyncer) runProvisioningQueries(ctx context.Context, queries []ProvisioningQuery, vars map[string]any, useTx bool) (map[string]any, error) {
 	l := ctxzap.Extract(ctx)
 
 	var committed bool
-	var executor executor = s.db
+	var executor executor
 
 	if useTx {
 		tx, err := s.db.BeginTx(ctx, nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		executor = tx
 
@@ -312,26 +550,85 @@ func (s *SQLSyncer) runProvisioningQueries(ctx context.Context, queries []string
 				}
 			}
 		}()
+	} else {
+		// Session state such as LAST_INSERT_ID() only carries over between statements on the same connection.
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		executor = conn
+
+		defer func() {
+			if err := conn.Close(); err != nil {
+				l.Error("failed to close provisioning connection", zap.Error(err))
+			}
+		}()
 	}
 
-	for _, q := range queries {
-		q, qArgs, err := s.prepareProvisioningQuery(q, vars)
-		if err != nil {
-			return err
+	queryErr := func(ii int, err error) error {
+		switch {
+		case useTx:
+			return fmt.Errorf("provisioning query %d: %w, rolling back", ii, err)
+		case ii > 0:
+			return fmt.Errorf("provisioning query %d: %w, earlier queries are not rolled back without a transaction", ii, err)
+		default:
+			return fmt.Errorf("provisioning query %d: %w", ii, err)
 		}
+	}
 
-		result, err := executor.ExecContext(ctx, q, qArgs...)
+	captured := make(map[string]any)
+	queryVars := make(map[string]any, len(vars))
+	maps.Copy(queryVars, vars)
+
+	for ii, query := range queries {
+		q, qArgs, err := s.prepareProvisioningQuery(query.String(), queryVars)
 		if err != nil {
-			return err
+			return nil, queryErr(ii, err)
 		}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			l.Error("failed to get rows affected", zap.Error(err))
+		// Without expect_rows, a query may affect at most one row.
+		expectRows := query.ExpectRows
+		if expectRows == nil {
+			maxRows := int64(1)
+			expectRows = &RowExpectation{Max: &maxRows}
 		}
 
-		if rowsAffected > 1 {
-			return errors.New("query affected more than one row, ending and rolling back")
+		// Statements that capture values are counted by the rows they return.
+		verb := "affected"
+		var rowsAffected int64
+		if len(query.Capture) > 0 {
+			verb = "returned"
+			var values map[string]any
+			rowsAffected, values, err = s.captureQuery(ctx, executor, q, qArgs, query.Capture)
+			if err != nil {
+				return nil, queryErr(ii, err)
+			}
+			if values == nil {
+				for name := range query.Capture {
+					queryVars[name] = missingCapture{query: ii}
+					delete(captured, name)
+				}
+			}
+			maps.Copy(queryVars, values)
+			maps.Copy(captured, values)
+		} else {
+			result, err := executor.ExecContext(ctx, q, qArgs...)
+			if err != nil {
+				return nil, queryErr(ii, err)
+			}
+
+			rowsAffected, err = result.RowsAffected()
+			if err != nil {
+				if query.ExpectRows != nil && !query.ExpectRows.Any {
+					return nil, queryErr(ii, fmt.Errorf("failed to get rows affected: %w", err))
+				}
+				l.Error("failed to get rows affected", zap.Error(err))
+				expectRows = &RowExpectation{Any: true}
+			}
+		}
+
+		if err := expectRows.Check(verb, rowsAffected); err != nil {
+			return nil, queryErr(ii, err)
 		}
 
 		l.Debug("query executed", zap.String("query", q), zap.Any("args", qArgs), zap.Int64("rows_affected", rowsAffected), zap.Bool("use_tx", useTx))
@@ -340,16 +637,65 @@ func (s *SQLSyncer) runProvisioningQueries(ctx context.Context, queries []string
 	if useTx {
 		tx, ok := executor.(*sql.Tx)
 		if !ok {
-			return errors.New("transactional executor required")
+			return nil, errors.New("transactional executor required")
 		}
 		err := tx.Commit()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		committed = true
 	}
 
-	return nil
+	return captured, nil
+}
+
+// captureQuery runs a provisioning statement that returns rows, and evaluates the capture mappings against the first
+// row. It returns the number of rows the statement returned, which is checked against expect_rows, and no captured
+// values when there were none.
+func (s *SQLSyncer) captureQuery(ctx context.Context, executor executor, q string, qArgs []any, capture map[string]string) (int64, map[string]any, error) {
+	rows, err := executor.QueryContext(ctx, q, qArgs...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	scanner, err := s.newRowScanner(rows)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var rowMap map[string]any
+	var count int64
+	for rows.Next() {
+		count++
+		if rowMap != nil {
+			continue
+		}
+
+		rowMap, err = scanner.scan()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	if rowMap == nil {
+		return 0, nil, nil
+	}
+
+	ret := make(map[string]any, len(capture))
+	inputs := s.env.SyncInputs(rowMap)
+	for name, expr := range capture {
+		v, err := s.env.Evaluate(ctx, expr, inputs)
+		if err != nil {
+			return 0, nil, fmt.Errorf("capture %s: %w", name, err)
+		}
+		ret[name] = v
+	}
+
+	return count, ret, nil
 }
 
 func (s *SQLSyncer) prepareQueryVars(ctx context.Context, inputs map[string]any, vars map[string]string) (map[string]any, error) {
@@ -377,6 +723,99 @@ func (s *SQLSyncer) prepareQueryVars(ctx context.Context, inputs map[string]any,
 	return ret, nil
 }
 
+// rowScanner scans the rows of a query result into maps keyed by column name, applying the normalize config.
+type rowScanner struct {
+	rows        *sql.Rows
+	dbEngine    database.DbEngine
+	normalize   *NormalizeConfig
+	columns     []string
+	columnTypes []*sql.ColumnType
+	uuidColumns []bool
+	values      []any
+	scanArgs    []any
+}
+
+func (s *SQLSyncer) newRowScanner(rows *sql.Rows) (*rowScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	normalize := s.fullConfig.Normalize
+	if normalize == nil {
+		normalize = &NormalizeConfig{}
+	}
+
+	var columnTypes []*sql.ColumnType
+	uuidColumns := make([]bool, len(columns))
+	if normalize.Values {
+		columnTypes, err = rows.ColumnTypes()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, colName := range columns {
+			uuidColumns[i] = slices.ContainsFunc(normalize.UUIDColumns, func(name string) bool {
+				return strings.EqualFold(name, colName)
+			})
+		}
+	}
+
+	ret := &rowScanner{
+		rows:        rows,
+		dbEngine:    s.dbEngine,
+		normalize:   normalize,
+		columns:     columns,
+		columnTypes: columnTypes,
+		uuidColumns: uuidColumns,
+		values:      make([]any, len(columns)),
+		scanArgs:    make([]any, len(columns)),
+	}
+	for i := range ret.values {
+		ret.scanArgs[i] = &ret.values[i]
+	}
+
+	return ret, nil
+}
+
+// scan reads the current row.
+func (r *rowScanner) scan() (map[string]any, error) {
+	if err := r.rows.Scan(r.scanArgs...); err != nil {
+		return nil, err
+	}
+
+	rowMap := make(map[string]any, len(r.columns))
+	for i, colName := range r.columns {
+		key := colName
+		if r.normalize.CaseInsensitiveColumns {
+			key = strings.ToLower(colName)
+		}
+
+		if r.columnTypes == nil || r.keepRaw(i) {
+			rowMap[key] = r.values[i]
+		} else {
+			rowMap[key] = normalizeValue(r.dbEngine, r.columnTypes[i].DatabaseTypeName(), r.uuidColumns[i], r.values[i])
+		}
+	}
+
+	return rowMap, nil
+}
+
+// keepRaw reports whether column i is a binary column whose values are kept as the driver returns them.
+func (r *rowScanner) keepRaw(i int) bool {
+	return r.normalize.rawBinary && !r.uuidColumns[i] && binaryColumnTypes[strings.ToUpper(r.columnTypes[i].DatabaseTypeName())]
+}
+
+// rawValue returns the value of the named column in the last scanned row as the driver returned it.
+func (r *rowScanner) rawValue(name string) (any, bool) {
+	for i, colName := range r.columns {
+		if colName == name || (r.normalize.CaseInsensitiveColumns && strings.EqualFold(name, colName)) {
+			return r.values[i], true
+		}
+	}
+	return nil, false
+}
+
 func (s *SQLSyncer) runQuery(
 	ctx context.Context,
 	pToken *pagination.Token,
@@ -401,17 +840,11 @@ func (s *SQLSyncer) runQuery(
 	}
 	defer rows.Close()
 
-	columns, err := rows.Columns()
+	scanner, err := s.newRowScanner(rows)
 	if err != nil {
 		return "", err
 	}
 
-	values := make([]interface{}, len(columns))
-	scanArgs := make([]interface{}, len(values))
-	for i := range values {
-		scanArgs[i] = &values[i]
-	}
-
 	var lastRowID any
 	rowCount := 0
 	for rows.Next() {
@@ -421,24 +854,19 @@ func (s *SQLSyncer) runQuery(
 			break
 		}
 
-		if err := rows.Scan(scanArgs...); err != nil {
+		rowMap, err := scanner.scan()
+		if err != nil {
 			return "", err
 		}
 
-		foundPaginationKey := false
-		rowMap := make(map[string]interface{})
-		for i, colName := range columns {
-			rowMap[colName] = values[i]
-			if pCtx != nil && pCtx.PrimaryKey == colName {
-				lastRowID = values[i]
-				foundPaginationKey = true
+		if pCtx != nil {
+			var found bool
+			lastRowID, found = scanner.rawValue(pCtx.PrimaryKey)
+			if !found {
+				return "", errors.New("primary key not found in query results")
 			}
 		}
 
-		if pCtx != nil && !foundPaginationKey {
-			return "", errors.New("primary key not found in query results")
-		}
-
 		ok, err := rowCallback(ctx, rowMap)
 		if err != nil {
 			return "", err
@@ -5,6 +5,9 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
 	"github.com/conductorone/baton-sql/pkg/database"
 )
 
@@ -42,6 +45,45 @@ func Test_parseToken(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:  "Token with list option",
+			token: "?<roles|list>",
+			want: &queryTokenOpts{
+				Key:  "roles",
+				List: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Token with list and unquoted options",
+			token:   "?<roles|list,unquoted>",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "Token with identifier option",
+			token: "?<username|identifier>",
+			want: &queryTokenOpts{
+				Key:        "username",
+				Identifier: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Token with literal option",
+			token: "?<password|literal>",
+			want: &queryTokenOpts{
+				Key:     "password",
+				Literal: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Token with identifier and literal options",
+			token:   "?<username|identifier,literal>",
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:    "Invalid token format",
 			token:   "invalid",
@@ -358,6 +400,274 @@ func Test_parseQueryOpts(t *testing.T) {
 			false,
 			false,
 		},
+		{
+			"Test list expansion (MySQL)",
+			database.MySQL,
+			args{
+				t.Context(),
+				"SELECT * FROM roles WHERE name IN (?<roles|list>) AND org = ?<org>",
+				nil,
+				map[string]any{
+					"roles": []string{"admin", "editor"},
+					"org":   "acme",
+				},
+			},
+			"SELECT * FROM roles WHERE name IN (?, ?) AND org = ?",
+			[]interface{}{"admin", "editor", "acme"},
+			false,
+			false,
+		},
+		{
+			"Test list expansion (MSSQL)",
+			database.MSSQL,
+			args{
+				t.Context(),
+				"SELECT * FROM roles WHERE org = ?<org> AND name IN (?<roles|list>)",
+				nil,
+				map[string]any{
+					"roles": []any{"admin", "editor"},
+					"org":   "acme",
+				},
+			},
+			"SELECT * FROM roles WHERE org = @p1 AND name IN (@p2, @p3)",
+			[]interface{}{"acme", "admin", "editor"},
+			false,
+			false,
+		},
+		{
+			"Test list binds as array (Postgres)",
+			database.PostgreSQL,
+			args{
+				t.Context(),
+				"SELECT * FROM roles WHERE name = ANY(?<roles|list>) AND org = ?<org>",
+				nil,
+				map[string]any{
+					"roles": []any{"admin", "editor"},
+					"org":   "acme",
+				},
+			},
+			"SELECT * FROM roles WHERE name = ANY($1) AND org = $2",
+			[]interface{}{[]string{"admin", "editor"}, "acme"},
+			false,
+			false,
+		},
+		{
+			"Test empty list expansion (MySQL)",
+			database.MySQL,
+			args{
+				t.Context(),
+				"SELECT * FROM roles WHERE name IN (?<roles|list>)",
+				nil,
+				map[string]any{
+					"roles": []string{},
+				},
+			},
+			"SELECT * FROM roles WHERE name IN (NULL)",
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test empty list binds as empty array (Postgres)",
+			database.PostgreSQL,
+			args{
+				t.Context(),
+				"SELECT * FROM roles WHERE name = ANY(?<roles|list>)",
+				nil,
+				map[string]any{
+					"roles": nil,
+				},
+			},
+			"SELECT * FROM roles WHERE name = ANY($1)",
+			[]interface{}{[]string{}},
+			false,
+			false,
+		},
+		{
+			"Test identifier quoting (MySQL)",
+			database.MySQL,
+			args{
+				t.Context(),
+				"GRANT ?<role|identifier> TO ?<username|identifier>",
+				nil,
+				map[string]any{
+					"role":     "app_reader",
+					"username": "alice@localhost",
+				},
+			},
+			"GRANT `app_reader` TO `alice@localhost`",
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test identifier quoting (Postgres)",
+			database.PostgreSQL,
+			args{
+				t.Context(),
+				"GRANT ?<role|identifier> TO ?<username|identifier>",
+				nil,
+				map[string]any{
+					"role":     "app_reader",
+					"username": "alice",
+				},
+			},
+			`GRANT "app_reader" TO "alice"`,
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test identifier quoting (MSSQL)",
+			database.MSSQL,
+			args{
+				t.Context(),
+				"ALTER ROLE ?<role|identifier> ADD MEMBER ?<username|identifier>",
+				nil,
+				map[string]any{
+					"role":     "app_reader",
+					"username": "alice",
+				},
+			},
+			"ALTER ROLE [app_reader] ADD MEMBER [alice]",
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test qualified identifier quoting (Postgres)",
+			database.PostgreSQL,
+			args{
+				t.Context(),
+				"GRANT SELECT ON ?<table|identifier> TO ?<username|identifier>",
+				nil,
+				map[string]any{
+					"table":    "reporting.orders",
+					"username": "alice",
+				},
+			},
+			`GRANT SELECT ON "reporting"."orders" TO "alice"`,
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test qualified identifier quoting (MSSQL)",
+			database.MSSQL,
+			args{
+				t.Context(),
+				"GRANT SELECT ON ?<table|identifier> TO ?<username|identifier>",
+				nil,
+				map[string]any{
+					"table":    "dbo.orders",
+					"username": "alice",
+				},
+			},
+			"GRANT SELECT ON [dbo].[orders] TO [alice]",
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test identifier rejects empty parts",
+			database.MySQL,
+			args{
+				t.Context(),
+				"GRANT SELECT ON ?<table|identifier> TO ?<username|identifier>",
+				nil,
+				map[string]any{
+					"table":    "reporting..orders",
+					"username": "alice",
+				},
+			},
+			"",
+			nil,
+			false,
+			true,
+		},
+		{
+			"Test identifier rejects injection",
+			database.PostgreSQL,
+			args{
+				t.Context(),
+				"CREATE USER ?<username|identifier>",
+				nil,
+				map[string]any{
+					"username": `alice"; DROP TABLE users; --`,
+				},
+			},
+			"",
+			nil,
+			false,
+			true,
+		},
+		{
+			"Test literal escaping (Postgres)",
+			database.PostgreSQL,
+			args{
+				t.Context(),
+				"CREATE USER ?<username|identifier> WITH PASSWORD ?<password|literal>",
+				nil,
+				map[string]any{
+					"username": "alice",
+					"password": `it's\secret`,
+				},
+			},
+			`CREATE USER "alice" WITH PASSWORD 'it''s\secret'`,
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test literal escaping (MySQL)",
+			database.MySQL,
+			args{
+				t.Context(),
+				"CREATE USER ?<username|identifier> IDENTIFIED BY ?<password|literal>",
+				nil,
+				map[string]any{
+					"username": "alice",
+					"password": `it's\secret`,
+				},
+			},
+			"CREATE USER `alice` IDENTIFIED BY 'it''s\\\\secret'",
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test literal escaping (MSSQL)",
+			database.MSSQL,
+			args{
+				t.Context(),
+				"CREATE LOGIN ?<username|identifier> WITH PASSWORD = ?<password|literal>",
+				nil,
+				map[string]any{
+					"username": "alice",
+					"password": "it's",
+				},
+			},
+			"CREATE LOGIN [alice] WITH PASSWORD = N'it''s'",
+			nil,
+			false,
+			false,
+		},
+		{
+			"Test list option with scalar value",
+			database.MySQL,
+			args{
+				t.Context(),
+				"SELECT * FROM roles WHERE name IN (?<roles|list>)",
+				nil,
+				map[string]any{
+					"roles": "admin",
+				},
+			},
+			"",
+			nil,
+			false,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -381,3 +691,206 @@ func Test_parseQueryOpts(t *testing.T) {
 		})
 	}
 }
+
+func Test_runProvisioningQueries_expectRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries string
+		noTx    bool
+		wantErr string
+		want    int
+	}{
+		{"default allows one row", "- DELETE FROM caps WHERE id = 1", false, "", 2},
+		{"default rejects several rows", "- DELETE FROM caps WHERE owner = 'alice'", false, "provisioning query 0: affected 2 rows, expected at most 1, rolling back", 3},
+		{"any", "- {default: \"DELETE FROM caps WHERE owner = 'alice'\", expect_rows: any}", false, "", 1},
+		{"exact count", "- {default: \"DELETE FROM caps WHERE owner = 'alice'\", expect_rows: 2}", false, "", 1},
+		{
+			"nothing affected",
+			"- DELETE FROM caps WHERE id = 1\n- {default: \"DELETE FROM caps WHERE id = 9\", expect_rows: {exact: 1}}",
+			false,
+			"provisioning query 1: affected 0 rows, expected exactly 1, rolling back",
+			3,
+		},
+		{
+			"without a transaction",
+			"- DELETE FROM caps WHERE id = 1\n- {default: \"DELETE FROM caps WHERE id = 9\", expect_rows: {min: 1}}",
+			true,
+			"provisioning query 1: affected 0 rows, expected at least 1, earlier queries are not rolled back",
+			2,
+		},
+		{"max", "- {default: \"DELETE FROM caps\", expect_rows: {max: 2}}", false, "affected 3 rows, expected at most 2", 3},
+		{"unbound var", "- DELETE FROM caps WHERE id = 1\n- DELETE FROM caps WHERE id = ?<id>", false, "provisioning query 1: unknown token ?<id>, rolling back", 3},
+		{
+			"failing statement without a transaction",
+			"- DELETE FROM caps WHERE id = 1\n- DELETE FROM missing",
+			true,
+			"provisioning query 1: SQL logic error: no such table: missing (1), earlier queries are not rolled back",
+			2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			db := newTestDB(t,
+				"CREATE TABLE caps (id INTEGER, owner TEXT)",
+				"INSERT INTO caps VALUES (1, 'alice'), (2, 'alice'), (3, 'bob')",
+			)
+
+			var queries []ProvisioningQuery
+			require.NoError(t, yaml.Unmarshal([]byte(tt.queries), &queries))
+
+			s := &SQLSyncer{db: db, dbEngine: database.SQLite}
+			_, err := s.runProvisioningQueries(ctx, queries, nil, !tt.noTx)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var n int
+			require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM caps").Scan(&n))
+			require.Equal(t, tt.want, n)
+		})
+	}
+
+	var q ProvisioningQuery
+	require.ErrorContains(t, yaml.Unmarshal([]byte("{default: SELECT 1, expect_rows: {exact: 1, max: 2}}"), &q), "cannot be combined")
+	require.ErrorContains(t, yaml.Unmarshal([]byte("{default: SELECT 1, expect_rows: some}"), &q), "expect_rows must be")
+	require.ErrorContains(t, yaml.Unmarshal([]byte("{expect_rows: any}"), &q), "query has no SQL statement")
+
+	// Only provisioning statements affect rows.
+	var listQuery Query
+	require.ErrorContains(t, yaml.Unmarshal([]byte("{default: SELECT 1, expect_rows: any}"), &listQuery), "expect_rows is only supported on provisioning queries")
+}
+
+func Test_runProvisioningQueries_capture(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT)",
+		"CREATE TABLE user_roles (user_id INTEGER, role TEXT)",
+	)
+	// Keep several connections open, so that statements outside a transaction would not share one by chance.
+	db.SetMaxIdleConns(4)
+
+	var queries []ProvisioningQuery
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- INSERT INTO users (username) VALUES (?<username>)
+- default: SELECT last_insert_rowid() AS id
+  capture:
+    user_id: .id
+    user_key: "'user-' + string(.id)"
+- INSERT INTO user_roles (user_id, role) VALUES (?<user_id>, ?<user_key>)
+`), &queries))
+
+	s := newTestSyncer(t, db, "user", ResourceType{}, Config{})
+
+	captured, err := s.runProvisioningQueries(ctx, queries, map[string]any{"username": "alice"}, true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"user_id": int64(1), "user_key": "user-1"}, captured)
+
+	captured, err = s.runProvisioningQueries(ctx, queries, map[string]any{"username": "bob"}, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"user_id": int64(2), "user_key": "user-2"}, captured)
+
+	var roles []string
+	rows, err := db.QueryContext(ctx, "SELECT role FROM user_roles ORDER BY user_id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var role string
+		require.NoError(t, rows.Scan(&role))
+		roles = append(roles, role)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []string{"user-1", "user-2"}, roles)
+
+	queries[1] = ProvisioningQuery{
+		Query:   Query{Default: "SELECT id FROM users WHERE username = 'nobody'"},
+		Capture: map[string]string{"user_id": ".id"},
+	}
+	_, err = s.runProvisioningQueries(ctx, queries, map[string]any{"username": "carol"}, true)
+	require.ErrorContains(t, err, "provisioning query 2: in token ?<user_id>: not captured, provisioning query 1 returned no rows")
+
+	// expect_rows counts the rows a capturing statement returns.
+	queries[1] = ProvisioningQuery{
+		Query:   Query{Default: "SELECT id FROM users"},
+		Capture: map[string]string{"user_id": ".id"},
+	}
+	_, err = s.runProvisioningQueries(ctx, queries, map[string]any{"username": "dave"}, true)
+	require.ErrorContains(t, err, "provisioning query 1: returned 3 rows, expected at most 1, rolling back")
+
+	// A statement may return no rows when its expectation allows it, as long as nothing uses its captures.
+	var optional []ProvisioningQuery
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- default: SELECT id FROM users WHERE username = ?<username>
+  expect_rows: any
+  capture:
+    existing_id: .id
+- INSERT INTO users (username) VALUES (?<username>)
+`), &optional))
+	captured, err = s.runProvisioningQueries(ctx, optional, map[string]any{"username": "erin"}, true)
+	require.NoError(t, err)
+	require.Empty(t, captured)
+
+	optional[0].ExpectRows = &RowExpectation{Min: new(int64)}
+	captured, err = s.runProvisioningQueries(ctx, optional, map[string]any{"username": "erin"}, true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"existing_id": int64(3)}, captured)
+
+	var listQuery Query
+	require.ErrorContains(t, yaml.Unmarshal([]byte("{default: SELECT 1, capture: {id: .id}}"), &listQuery), "capture is only supported on provisioning queries")
+}
+
+func TestSQLSyncer_runQuery_emptyList(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE roles (name TEXT)",
+		"INSERT INTO roles VALUES ('admin'), ('editor')",
+	)
+
+	s := &SQLSyncer{db: db, dbEngine: database.SQLite}
+	count := func(query string) int {
+		n := 0
+		_, err := s.runQuery(ctx, nil, query, nil, map[string]any{"roles": []string{}}, func(context.Context, map[string]any) (bool, error) {
+			n++
+			return true, nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	require.Equal(t, 0, count("SELECT name FROM roles WHERE name IN (?<roles|list>)"))
+	// An empty exclusion list expands to NOT IN (NULL), which matches no rows rather than all of them.
+	require.Equal(t, 0, count("SELECT name FROM roles WHERE name NOT IN (?<roles|list>)"))
+}
+
+func TestSQLSyncer_runQuery_uuidColumns(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (external_id BLOB, digest BLOB)",
+		"INSERT INTO users VALUES (x'123e4567e89b12d3a456426614174000', x'900150983cd24fb0d6963f7d28e17f72')",
+	)
+
+	s := &SQLSyncer{
+		db:       db,
+		dbEngine: database.SQLite,
+		fullConfig: Config{
+			Normalize: &NormalizeConfig{Values: true, UUIDColumns: []string{"EXTERNAL_ID"}},
+		},
+	}
+
+	var row map[string]any
+	_, err := s.runQuery(ctx, nil, "SELECT external_id, digest FROM users", nil, nil, func(_ context.Context, rowMap map[string]any) (bool, error) {
+		row = rowMap
+		return true, nil
+	})
+	require.NoError(t, err)
+
+	// Only listed columns are formatted as UUIDs. Other 16-byte values, such as MD5 digests, stay hex.
+	require.Equal(t, "123e4567-e89b-12d3-a456-426614174000", row["external_id"])
+	require.Equal(t, "900150983cd24fb0d6963f7d28e17f72", row["digest"])
+}
@@ -3,10 +3,13 @@ package bsql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -26,7 +29,7 @@ func (s *SQLSyncer) List(ctx context.Context, parentResourceID *v2.ResourceId, p
 		return nil, "", nil, err
 	}
 
-	npt, err := s.runQuery(ctx, pToken, s.config.List.Query, s.config.List.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
+	npt, err := s.runQuery(ctx, pToken, s.config.List.Query.String(), s.config.List.Pagination, queryVars, func(ctx context.Context, rowMap map[string]any) (bool, error) {
 		r, err := s.mapResource(ctx, rowMap)
 		if err != nil {
 			return false, err
@@ -41,23 +44,133 @@ func (s *SQLSyncer) List(ctx context.Context, parentResourceID *v2.ResourceId, p
 	return ret, npt, nil, nil
 }
 
-func (s *SQLSyncer) fetchTraits() map[string]bool {
-	traits := make(map[string]bool)
-	mapTraits := s.config.List.Map.Traits
-	if mapTraits != nil {
-		switch {
-		case mapTraits.User != nil:
-			traits[userTraitType] = true
+// mapProfile evaluates the profile mappings of a trait. Nested sections become nested objects. Values are strings
+// unless typed profiles are enabled, in which case they keep the type of the expression result.
+func (s *SQLSyncer) mapProfile(ctx context.Context, profile map[string]any, inputs map[string]any) (map[string]any, error) {
+	ret := make(map[string]any, len(profile))
+	for profileKey, profileValue := range profile {
+		v, err := s.evaluateProfileValue(ctx, profileKey, profileValue, inputs)
+		if err != nil {
+			return nil, err
+		}
+		ret[profileKey] = v
+	}
 
-		case mapTraits.Group != nil:
-			traits[groupTraitType] = true
+	return ret, nil
+}
 
-		case mapTraits.Role != nil:
-			traits[roleTraitType] = true
+func (s *SQLSyncer) evaluateProfileValue(ctx context.Context, key string, mapping any, inputs map[string]any) (any, error) {
+	switch m := mapping.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(m))
+		for k, v := range m {
+			nested, err := s.evaluateProfileValue(ctx, key+"."+k, v, inputs)
+			if err != nil {
+				return nil, err
+			}
+			ret[k] = nested
+		}
+		return ret, nil
+
+	case string, bool, int, float64:
+		expr := fmt.Sprint(m)
+		if !s.fullConfig.TypedProfiles {
+			v, err := s.env.EvaluateString(ctx, expr, inputs)
+			if err != nil {
+				return nil, fmt.Errorf("profile.%s: %w", key, err)
+			}
+			return v, nil
+		}
 
-		case mapTraits.App != nil:
-			traits[appTraitType] = true
+		v, err := s.env.EvaluateValue(ctx, expr, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("profile.%s: %w", key, err)
 		}
+		return v.AsInterface(), nil
+
+	default:
+		return nil, fmt.Errorf("profile.%s: expected an expression or a nested section, got %T", key, mapping)
+	}
+}
+
+// evaluateTime evaluates a time mapping. Timestamps are used directly and strings are parsed in the formats of the
+// database engine. Values that cannot be parsed are logged and skipped.
+func (s *SQLSyncer) evaluateTime(ctx context.Context, field string, mapping string, inputs map[string]any) (*time.Time, error) {
+	v, err := s.env.Evaluate(ctx, mapping, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := timeValue(v, s.dbEngine)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to parse time", zap.String("field", field), zap.Any("value", v), zap.Error(err))
+		t = nil
+	}
+	return t, nil
+}
+
+func (s *SQLSyncer) mapStructuredName(ctx context.Context, mapping *StructuredNameMapping, inputs map[string]any) (*v2.UserTrait_StructuredName, error) {
+	ret := &v2.UserTrait_StructuredName{}
+
+	for _, field := range []struct {
+		mapping string
+		target  *string
+	}{
+		{mapping.GivenName, &ret.GivenName},
+		{mapping.FamilyName, &ret.FamilyName},
+		{mapping.Prefix, &ret.Prefix},
+		{mapping.Suffix, &ret.Suffix},
+	} {
+		if field.mapping == "" {
+			continue
+		}
+		v, err := s.env.EvaluateString(ctx, field.mapping, inputs)
+		if err != nil {
+			return nil, err
+		}
+		*field.target = v
+	}
+
+	for _, m := range mapping.MiddleNames {
+		v, err := s.env.EvaluateString(ctx, m, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			ret.MiddleNames = append(ret.MiddleNames, v)
+		}
+	}
+
+	return ret, nil
+}
+
+// fetchTraits returns every trait configured for the resource type, in the same order as the traits declared on the
+// resource type.
+func (s *SQLSyncer) fetchTraits() []string {
+	var traits []string
+	mapTraits := s.config.List.Map.Traits
+	if mapTraits == nil {
+		return traits
+	}
+
+	if mapTraits.User != nil {
+		traits = append(traits, userTraitType)
+	}
+
+	if mapTraits.Group != nil {
+		traits = append(traits, groupTraitType)
+	}
+
+	if mapTraits.Role != nil {
+		traits = append(traits, roleTraitType)
+	}
+
+	if mapTraits.App != nil {
+		traits = append(traits, appTraitType)
+	}
+
+	if mapTraits.Secret != nil {
+		traits = append(traits, secretTraitType)
 	}
 
 	return traits
@@ -132,35 +245,57 @@ func (s *SQLSyncer) mapUserTrait(ctx context.Context, r *v2.Resource, rowMap map
 		}
 	}
 
-	profile := make(map[string]interface{})
-	for profileKey, profileValue := range mappings.Profile {
-		v, err := s.env.EvaluateString(ctx, profileValue, inputs)
+	profile, err := s.mapProfile(ctx, mappings.Profile, inputs)
+	if err != nil {
+		return err
+	}
+
+	// Last Login
+	if mappings.LastLogin != "" {
+		lastLoginTime, err := s.evaluateTime(ctx, "last_login", mappings.LastLogin, inputs)
 		if err != nil {
 			return err
 		}
-		profile[profileKey] = v
+		if lastLoginTime != nil {
+			opts = append(opts, sdkResource.WithLastLogin(*lastLoginTime))
+		}
 	}
 
-	if len(profile) > 0 {
-		opts = append(opts, sdkResource.WithUserProfile(profile))
+	// Created At
+	if mappings.CreatedAt != "" {
+		createdAt, err := s.evaluateTime(ctx, "created_at", mappings.CreatedAt, inputs)
+		if err != nil {
+			return err
+		}
+		if createdAt != nil {
+			opts = append(opts, sdkResource.WithCreatedAt(*createdAt))
+		}
 	}
 
-	// Last Login
-	if mappings.LastLogin != "" {
-		lastLoginValue, err := s.env.EvaluateString(ctx, mappings.LastLogin, inputs)
+	// MFA and SSO
+	if mappings.MfaEnabled != "" {
+		mfaEnabled, err := s.env.EvaluateBool(ctx, mappings.MfaEnabled, inputs)
 		if err != nil {
 			return err
 		}
+		opts = append(opts, sdkResource.WithMFAStatus(&v2.UserTrait_MFAStatus{MfaEnabled: mfaEnabled}))
+	}
 
-		if lastLoginValue != "" {
-			// Try to parse the last login date using dbEngine to determine format
-			lastLoginTime, err := parseTimeWithEngine(lastLoginValue, s.dbEngine)
-			if err != nil {
-				l.Warn("failed to parse last login time", zap.String("last_login", lastLoginValue), zap.Error(err))
-			} else {
-				opts = append(opts, sdkResource.WithLastLogin(*lastLoginTime))
-			}
+	if mappings.SsoEnabled != "" {
+		ssoEnabled, err := s.env.EvaluateBool(ctx, mappings.SsoEnabled, inputs)
+		if err != nil {
+			return err
 		}
+		opts = append(opts, sdkResource.WithSSOStatus(&v2.UserTrait_SSOStatus{SsoEnabled: ssoEnabled}))
+	}
+
+	// Structured Name
+	if mappings.StructuredName != nil {
+		name, err := s.mapStructuredName(ctx, mappings.StructuredName, inputs)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sdkResource.WithStructuredName(name))
 	}
 
 	// Employee ID
@@ -252,6 +387,18 @@ func (s *SQLSyncer) mapUserTrait(ctx context.Context, r *v2.Resource, rowMap map
 		}
 	}
 
+	if len(profile) > 0 {
+		opts = append(opts, sdkResource.WithUserProfile(profile))
+	}
+
+	icon, err := s.mapIcon(ctx, mappings.Icon, inputs)
+	if err != nil {
+		return err
+	}
+	if icon != nil {
+		opts = append(opts, sdkResource.WithUserIcon(icon))
+	}
+
 	t, err := sdkResource.NewUserTrait(opts...)
 	if err != nil {
 		return err
@@ -283,19 +430,23 @@ func (s *SQLSyncer) mapAppTrait(ctx context.Context, r *v2.Resource, rowMap map[
 		opts = append(opts, sdkResource.WithAppHelpURL(v))
 	}
 
-	profile := make(map[string]interface{})
-	for profileKey, profileValue := range mappings.Profile {
-		v, err := s.env.EvaluateString(ctx, profileValue, inputs)
-		if err != nil {
-			return err
-		}
-		profile[profileKey] = v
+	profile, err := s.mapProfile(ctx, mappings.Profile, inputs)
+	if err != nil {
+		return err
 	}
 
 	if len(profile) > 0 {
 		opts = append(opts, sdkResource.WithAppProfile(profile))
 	}
 
+	icon, err := s.mapIcon(ctx, mappings.Icon, inputs)
+	if err != nil {
+		return err
+	}
+	if icon != nil {
+		opts = append(opts, sdkResource.WithAppIcon(icon))
+	}
+
 	t, err := sdkResource.NewAppTrait(opts...)
 	if err != nil {
 		return err
@@ -315,18 +466,22 @@ func (s *SQLSyncer) mapGroupTrait(ctx context.Context, r *v2.Resource, rowMap ma
 
 	var opts []sdkResource.GroupTraitOption
 
-	profile := make(map[string]interface{})
-	for profileKey, profileValue := range mappings.Profile {
-		v, err := s.env.EvaluateString(ctx, profileValue, inputs)
-		if err != nil {
-			return err
-		}
-		profile[profileKey] = v
+	profile, err := s.mapProfile(ctx, mappings.Profile, inputs)
+	if err != nil {
+		return err
 	}
 	if len(profile) > 0 {
 		opts = append(opts, sdkResource.WithGroupProfile(profile))
 	}
 
+	icon, err := s.mapIcon(ctx, mappings.Icon, inputs)
+	if err != nil {
+		return err
+	}
+	if icon != nil {
+		opts = append(opts, sdkResource.WithGroupIcon(icon))
+	}
+
 	t, err := sdkResource.NewGroupTrait(opts...)
 	if err != nil {
 		return err
@@ -346,13 +501,9 @@ func (s *SQLSyncer) mapRoleTrait(ctx context.Context, r *v2.Resource, rowMap map
 
 	var opts []sdkResource.RoleTraitOption
 
-	profile := make(map[string]interface{})
-	for profileKey, profileValue := range mappings.Profile {
-		v, err := s.env.EvaluateString(ctx, profileValue, inputs)
-		if err != nil {
-			return err
-		}
-		profile[profileKey] = v
+	profile, err := s.mapProfile(ctx, mappings.Profile, inputs)
+	if err != nil {
+		return err
 	}
 	if len(profile) > 0 {
 		opts = append(opts, sdkResource.WithRoleProfile(profile))
@@ -370,14 +521,101 @@ func (s *SQLSyncer) mapRoleTrait(ctx context.Context, r *v2.Resource, rowMap map
 	return nil
 }
 
-func (s *SQLSyncer) mapTraits(ctx context.Context, r *v2.Resource, rowMap map[string]any) error {
-	l := ctxzap.Extract(ctx)
+func (s *SQLSyncer) mapSecretTrait(ctx context.Context, r *v2.Resource, rowMap map[string]any) error {
+	inputs := s.env.SyncInputs(rowMap)
+
+	mappings := s.config.List.Map.Traits.Secret
+
+	var opts []sdkResource.SecretTraitOption
 
-	for trait, enabled := range s.fetchTraits() {
-		if !enabled {
+	for _, m := range []struct {
+		field   string
+		mapping string
+		opt     func(time.Time) sdkResource.SecretTraitOption
+	}{
+		{"created_at", mappings.CreatedAt, sdkResource.WithSecretCreatedAt},
+		{"expires_at", mappings.ExpiresAt, sdkResource.WithSecretExpiresAt},
+		{"last_used_at", mappings.LastUsedAt, sdkResource.WithSecretLastUsedAt},
+	} {
+		if m.mapping == "" {
 			continue
 		}
 
+		t, err := s.evaluateTime(ctx, m.field, m.mapping, inputs)
+		if err != nil {
+			return err
+		}
+		if t != nil {
+			opts = append(opts, m.opt(*t))
+		}
+	}
+
+	identityID, err := s.mapSecretPrincipal(ctx, mappings.IdentityID, mappings.IdentityType, inputs)
+	if err != nil {
+		return err
+	}
+	if identityID != nil {
+		opts = append(opts, sdkResource.WithSecretIdentityID(identityID))
+	}
+
+	createdByID, err := s.mapSecretPrincipal(ctx, mappings.CreatedByID, mappings.CreatedByType, inputs)
+	if err != nil {
+		return err
+	}
+	if createdByID != nil {
+		opts = append(opts, sdkResource.WithSecretCreatedByID(createdByID))
+	}
+
+	t, err := sdkResource.NewSecretTrait(opts...)
+	if err != nil {
+		return err
+	}
+
+	profile, err := s.mapProfile(ctx, mappings.Profile, inputs)
+	if err != nil {
+		return err
+	}
+	if len(profile) > 0 {
+		// The SDK has no profile option for secret traits.
+		t.Profile, err = structpb.NewStruct(profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	annos := annotations.Annotations(r.Annotations)
+	annos.Update(t)
+	r.Annotations = annos
+
+	return nil
+}
+
+// mapSecretPrincipal evaluates the ID of a resource linked to a secret, such as its owner. Resource types default to
+// user. It returns nil when the ID evaluates to an empty string.
+func (s *SQLSyncer) mapSecretPrincipal(ctx context.Context, idMapping string, resourceType string, inputs map[string]any) (*v2.ResourceId, error) {
+	if idMapping == "" {
+		return nil, nil
+	}
+
+	id, err := s.env.EvaluateString(ctx, idMapping, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, nil
+	}
+
+	if resourceType == "" {
+		resourceType = userTraitType
+	}
+
+	return &v2.ResourceId{ResourceType: resourceType, Resource: id}, nil
+}
+
+func (s *SQLSyncer) mapTraits(ctx context.Context, r *v2.Resource, rowMap map[string]any) error {
+	l := ctxzap.Extract(ctx)
+
+	for _, trait := range s.fetchTraits() {
 		switch trait {
 		case userTraitType:
 			if err := s.mapUserTrait(ctx, r, rowMap); err != nil {
@@ -395,6 +633,10 @@ func (s *SQLSyncer) mapTraits(ctx context.Context, r *v2.Resource, rowMap map[st
 			if err := s.mapGroupTrait(ctx, r, rowMap); err != nil {
 				return err
 			}
+		case secretTraitType:
+			if err := s.mapSecretTrait(ctx, r, rowMap); err != nil {
+				return err
+			}
 		default:
 			l.Warn("unexpected trait type in mapping", zap.String("trait", trait))
 			continue
@@ -461,5 +703,11 @@ func (s *SQLSyncer) getMappedResource(ctx context.Context, r *v2.Resource, rowMa
 		r.Description = v
 	}
 
+	annos := annotations.Annotations(r.Annotations)
+	if err := s.mapAnnotations(ctx, annotationTargetResource, &annos, mapping.Annotations, inputs); err != nil {
+		return err
+	}
+	r.Annotations = annos
+
 	return nil
 }
@@ -0,0 +1,266 @@
+package bsql
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+	"github.com/conductorone/baton-sql/pkg/database"
+)
+
+func TestSQLSyncer_mapUserTrait(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	newSyncer := func(mapping *UserTraitMapping) *SQLSyncer {
+		return &SQLSyncer{
+			resourceType: &v2.ResourceType{Id: "user"},
+			dbEngine:     database.MySQL,
+			env:          env,
+			config: ResourceType{
+				List: &ListQuery{
+					Map: &ResourceMapping{
+						Traits: &Traits{User: mapping},
+					},
+				},
+			},
+		}
+	}
+
+	row := map[string]any{
+		"login":       "jdoe",
+		"first_name":  "Jane",
+		"middle_name": "Q",
+		"last_name":   "Doe",
+		"created":     "2024-01-15 09:30:00",
+		"mfa":         int64(1),
+		"sso":         "false",
+		"manager":     "boss",
+	}
+
+	t.Run("All fields", func(t *testing.T) {
+		s := newSyncer(&UserTraitMapping{
+			Login:      ".login",
+			CreatedAt:  ".created",
+			MfaEnabled: ".mfa",
+			SsoEnabled: ".sso",
+			ManagerID:  ".manager",
+			StructuredName: &StructuredNameMapping{
+				GivenName:   ".first_name",
+				FamilyName:  ".last_name",
+				MiddleNames: []string{".middle_name", "''"},
+				Suffix:      "'Jr.'",
+			},
+		})
+
+		r := &v2.Resource{}
+		require.NoError(t, s.mapUserTrait(ctx, r, row))
+
+		ut, err := sdkResource.GetUserTrait(r)
+		require.NoError(t, err)
+		require.Equal(t, "jdoe", ut.GetLogin())
+		require.Equal(t, time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC), ut.GetCreatedAt().AsTime())
+		require.True(t, ut.GetMfaStatus().GetMfaEnabled())
+		require.NotNil(t, ut.GetSsoStatus())
+		require.False(t, ut.GetSsoStatus().GetSsoEnabled())
+		require.Equal(t, "Jane", ut.GetStructuredName().GetGivenName())
+		require.Equal(t, "Doe", ut.GetStructuredName().GetFamilyName())
+		require.Equal(t, []string{"Q"}, ut.GetStructuredName().GetMiddleNames())
+		require.Equal(t, "Jr.", ut.GetStructuredName().GetSuffix())
+
+		// The manager is kept in the profile even when no other profile attributes are mapped.
+		require.Equal(t, "boss", ut.GetProfile().GetFields()["manager_id"].GetStringValue())
+	})
+
+	t.Run("Unmapped fields are unset", func(t *testing.T) {
+		s := newSyncer(&UserTraitMapping{Login: ".login"})
+
+		r := &v2.Resource{}
+		require.NoError(t, s.mapUserTrait(ctx, r, row))
+
+		ut, err := sdkResource.GetUserTrait(r)
+		require.NoError(t, err)
+		require.Nil(t, ut.GetCreatedAt())
+		require.Nil(t, ut.GetMfaStatus())
+		require.Nil(t, ut.GetSsoStatus())
+		require.Nil(t, ut.GetStructuredName())
+		require.Nil(t, ut.GetProfile())
+	})
+
+	t.Run("Unparseable created_at is skipped", func(t *testing.T) {
+		s := newSyncer(&UserTraitMapping{CreatedAt: "'not a time'"})
+
+		r := &v2.Resource{}
+		require.NoError(t, s.mapUserTrait(ctx, r, row))
+
+		ut, err := sdkResource.GetUserTrait(r)
+		require.NoError(t, err)
+		require.Nil(t, ut.GetCreatedAt())
+	})
+
+	profileMapping := `
+profile:
+  login: .login
+  is_admin: .role == 'admin'
+  level: .level
+  groups: .groups.split(',')
+  enabled: true
+  access:
+    roles: "[.role]"
+    weight: 2
+`
+	profileRow := map[string]any{
+		"login":  "jdoe",
+		"role":   "admin",
+		"level":  int64(3),
+		"groups": "eng,ops",
+	}
+
+	t.Run("String profile", func(t *testing.T) {
+		mapping := &UserTraitMapping{}
+		require.NoError(t, yaml.Unmarshal([]byte(profileMapping), mapping))
+		s := newSyncer(mapping)
+
+		r := &v2.Resource{}
+		require.NoError(t, s.mapUserTrait(ctx, r, profileRow))
+
+		ut, err := sdkResource.GetUserTrait(r)
+		require.NoError(t, err)
+		profile := ut.GetProfile().AsMap()
+		require.Equal(t, "jdoe", profile["login"])
+		require.Equal(t, "3", profile["level"])
+		require.Equal(t, "[eng ops]", profile["groups"])
+		require.Equal(t, map[string]any{"roles": "[admin]", "weight": "2"}, profile["access"])
+	})
+
+	t.Run("Typed profile", func(t *testing.T) {
+		mapping := &UserTraitMapping{}
+		require.NoError(t, yaml.Unmarshal([]byte(profileMapping), mapping))
+		s := newSyncer(mapping)
+		s.fullConfig.TypedProfiles = true
+
+		r := &v2.Resource{}
+		require.NoError(t, s.mapUserTrait(ctx, r, profileRow))
+
+		ut, err := sdkResource.GetUserTrait(r)
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{
+			"login":    "jdoe",
+			"is_admin": true,
+			"level":    float64(3),
+			"groups":   []any{"eng", "ops"},
+			"enabled":  true,
+			"access": map[string]any{
+				"roles":  []any{"admin"},
+				"weight": float64(2),
+			},
+		}, ut.GetProfile().AsMap())
+	})
+
+	t.Run("Invalid profile value", func(t *testing.T) {
+		s := newSyncer(&UserTraitMapping{Profile: map[string]any{"access": map[string]any{"roles": []any{".role"}}}})
+
+		err := s.mapUserTrait(ctx, &v2.Resource{}, profileRow)
+		require.ErrorContains(t, err, "profile.access.roles: expected an expression or a nested section, got []interface {}")
+	})
+}
+
+func TestSQLSyncer_mapTraits(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	s := &SQLSyncer{
+		resourceType: &v2.ResourceType{Id: "team"},
+		dbEngine:     database.MySQL,
+		env:          env,
+		config: ResourceType{
+			List: &ListQuery{
+				Map: &ResourceMapping{
+					Traits: &Traits{
+						Role:  &RoleTraitMapping{Profile: map[string]any{"permissions": ".permissions"}},
+						Group: &GroupTraitMapping{Profile: map[string]any{"lead": ".lead"}},
+					},
+				},
+			},
+		},
+	}
+
+	r := &v2.Resource{}
+	require.NoError(t, s.mapTraits(ctx, r, map[string]any{"permissions": "deploy", "lead": "jdoe"}))
+
+	// Traits are added in the order they are declared on the resource type.
+	require.Len(t, r.GetAnnotations(), 2)
+	require.True(t, r.GetAnnotations()[0].MessageIs(&v2.GroupTrait{}))
+	require.True(t, r.GetAnnotations()[1].MessageIs(&v2.RoleTrait{}))
+
+	gt, err := sdkResource.GetGroupTrait(r)
+	require.NoError(t, err)
+	require.Equal(t, "jdoe", gt.GetProfile().GetFields()["lead"].GetStringValue())
+
+	rt, err := sdkResource.GetRoleTrait(r)
+	require.NoError(t, err)
+	require.Equal(t, "deploy", rt.GetProfile().GetFields()["permissions"].GetStringValue())
+}
+
+func TestSQLSyncer_mapSecretTrait(t *testing.T) {
+	ctx := t.Context()
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	s := &SQLSyncer{
+		resourceType: &v2.ResourceType{Id: "api_key"},
+		dbEngine:     database.MySQL,
+		env:          env,
+		config: ResourceType{
+			List: &ListQuery{
+				Map: &ResourceMapping{
+					Traits: &Traits{
+						Secret: &SecretTraitMapping{
+							Profile:       map[string]any{"scopes": ".scopes"},
+							CreatedAt:     ".created_at",
+							ExpiresAt:     ".expires_at",
+							LastUsedAt:    ".last_used_at",
+							IdentityID:    ".owner_id",
+							CreatedByID:   ".created_by",
+							CreatedByType: "'service_account'",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	r := &v2.Resource{}
+	require.NoError(t, s.mapSecretTrait(ctx, r, map[string]any{
+		"scopes":       "read,write",
+		"created_at":   "2024-01-15 09:30:00",
+		"expires_at":   time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+		"last_used_at": nil,
+		"owner_id":     int64(42),
+		"created_by":   "",
+	}))
+
+	annos := annotations.Annotations(r.GetAnnotations())
+	st := &v2.SecretTrait{}
+	ok, err := annos.Pick(st)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC), st.GetCreatedAt().AsTime())
+	require.Equal(t, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), st.GetExpiresAt().AsTime())
+	require.Nil(t, st.GetLastUsedAt())
+	require.Equal(t, "user", st.GetIdentityId().GetResourceType())
+	require.Equal(t, "42", st.GetIdentityId().GetResource())
+	require.Nil(t, st.GetCreatedById())
+	require.Equal(t, "read,write", st.GetProfile().GetFields()["scopes"].GetStringValue())
+}
@@ -3,6 +3,7 @@ package bsql
 import (
 	"context"
 	"database/sql"
+	"sync"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
@@ -11,10 +12,11 @@ import (
 )
 
 const (
-	userTraitType  = "user"
-	appTraitType   = "app"
-	groupTraitType = "group"
-	roleTraitType  = "role"
+	userTraitType   = "user"
+	appTraitType    = "app"
+	groupTraitType  = "group"
+	roleTraitType   = "role"
+	secretTraitType = "secret"
 )
 
 type SQLSyncer struct {
@@ -24,12 +26,20 @@ type SQLSyncer struct {
 	config       ResourceType
 	env          *bcel.Env
 	fullConfig   Config
+
+	bulkMtx     sync.Mutex
+	bulkResults map[any]*bulkRowStore
 }
 
 func (s *SQLSyncer) ResourceType(ctx context.Context) *v2.ResourceType {
 	return s.resourceType
 }
 
+// Close releases resources held by the syncer, such as cached bulk query results.
+func (s *SQLSyncer) Close() error {
+	return s.ResetBulkResults()
+}
+
 func (c Config) GetSQLSyncers(ctx context.Context, db *sql.DB, dbEngine database.DbEngine, celEnv *bcel.Env) ([]connectorbuilder.ResourceSyncer, error) {
 	var ret []connectorbuilder.ResourceSyncer
 	for rtID, rtConfig := range c.ResourceTypes {
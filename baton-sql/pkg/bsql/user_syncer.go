@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -126,12 +127,15 @@ func (s *userSyncer) CreateAccount(
 
 	// Execute account creation queries
 	useTransaction := !provisioningConfig.Create.NoTransaction
-	if err := s.runProvisioningQueries(ctx, provisioningConfig.Create.Queries, queryInputs, useTransaction); err != nil {
+	captured, err := s.runProvisioningQueries(ctx, provisioningConfig.Create.Queries, queryInputs, useTransaction)
+	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	// Validate the created account
-	accountResource, err := s.validateAccount(ctx, provisioningConfig, queryInputs)
+	// Validate the created account, with the values captured from the creation queries
+	validateInputs := maps.Clone(queryInputs)
+	maps.Copy(validateInputs, captured)
+	accountResource, err := s.validateAccount(ctx, provisioningConfig, validateInputs)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to validate created account: %w", err)
 	}
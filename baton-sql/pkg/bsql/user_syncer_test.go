@@ -6,6 +6,9 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/conductorone/baton-sql/pkg/bcel"
+	"github.com/conductorone/baton-sql/pkg/database"
 )
 
 func TestUserSyncer_prepareQueryInputs_KeyCollision(t *testing.T) {
@@ -166,3 +169,63 @@ func TestUserSyncer_prepareQueryInputs_KeyCollision(t *testing.T) {
 		})
 	}
 }
+
+func TestUserSyncer_CreateAccount_Capture(t *testing.T) {
+	ctx := t.Context()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT)",
+		"CREATE TABLE user_roles (user_id INTEGER, role TEXT)",
+		"INSERT INTO users (username) VALUES ('existing')",
+	)
+
+	c, err := Parse([]byte(`
+resource_types:
+  user:
+    name: User
+    list:
+      query: SELECT id, username FROM users
+      map:
+        id: .id
+        display_name: .username
+    account_provisioning:
+      schema:
+        - name: username
+          type: string
+          required: true
+      credentials:
+        no_password:
+          preferred: true
+      create:
+        queries:
+          - default: INSERT INTO users (username) VALUES (?<username>) RETURNING id
+            capture:
+              user_id: .id
+          - INSERT INTO user_roles (user_id, role) VALUES (?<user_id>, 'member')
+      validate:
+        vars:
+          user_id: user_id
+        query: SELECT id, username FROM users WHERE id = ?<user_id>
+`))
+	require.NoError(t, err)
+	require.NoError(t, c.ResolveQueries(database.SQLite))
+
+	env, err := bcel.NewEnv(ctx)
+	require.NoError(t, err)
+
+	s := newUserSyncer(&v2.ResourceType{Id: "user"}, c.ResourceTypes["user"], db, database.SQLite, env, *c)
+
+	resp, _, _, err := s.CreateAccount(ctx, &v2.AccountInfo{
+		Profile: &structpb.Struct{Fields: map[string]*structpb.Value{"username": structpb.NewStringValue("alice")}},
+	}, &v2.CredentialOptions{Options: &v2.CredentialOptions_NoPassword_{NoPassword: &v2.CredentialOptions_NoPassword{}}})
+	require.NoError(t, err)
+
+	result, ok := resp.(*v2.CreateAccountResponse_SuccessResult)
+	require.True(t, ok)
+	require.Equal(t, "2", result.GetResource().GetId().GetResource())
+	require.Equal(t, "alice", result.GetResource().GetDisplayName())
+
+	var userID int64
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT user_id FROM user_roles WHERE role = 'member'").Scan(&userID))
+	require.Equal(t, int64(2), userID)
+}
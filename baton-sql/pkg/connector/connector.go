@@ -20,10 +20,18 @@ type Connector struct {
 	db       *sql.DB
 	dbEngine database.DbEngine
 	celEnv   *bcel.Env
+	lookups  *bsql.LookupTables
+	syncers  []connectorbuilder.ResourceSyncer
 }
 
 func (c *Connector) Close() error {
 	var errs error
+	for _, syncer := range c.syncers {
+		if closer, ok := syncer.(io.Closer); ok {
+			errs = errors.Join(errs, closer.Close())
+		}
+	}
+
 	if c.db != nil {
 		err := c.db.Close()
 		if err != nil {
@@ -40,13 +48,14 @@ func (c *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.Reso
 		return nil
 	}
 
+	c.syncers = syncers
 	return syncers
 }
 
-// Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
-// It streams a response, always starting with a metadata object, following by chunked payloads for the asset.
+// Asset takes an input AssetRef from an icon mapping and fetches it with the asset query of its resource type.
+// It returns the content type and the contents, which the SDK streams as a metadata object followed by chunked payloads.
 func (c *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.ReadCloser, error) {
-	return "", nil, nil
+	return c.config.GetAsset(ctx, c.db, c.dbEngine, c.celEnv, asset)
 }
 
 // Metadata returns metadata about the connector.
@@ -75,10 +84,31 @@ func (c *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error)
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
+// It is also called at the start of every sync, so lookup tables and cached bulk query results are dropped here to be
+// loaded again with fresh data.
 func (c *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	if c.lookups != nil {
+		c.lookups.Reset()
+	}
+
+	var errs error
+	for _, syncer := range c.syncers {
+		if s, ok := syncer.(bulkResetter); ok {
+			errs = errors.Join(errs, s.ResetBulkResults())
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
 	return nil, nil
 }
 
+// bulkResetter is implemented by syncers that cache bulk query results.
+type bulkResetter interface {
+	ResetBulkResults() error
+}
+
 // New returns a new instance of the connector.
 func New(ctx context.Context, configFilePath string) (*Connector, error) {
 	c, err := bsql.LoadConfigFromFile(configFilePath)
@@ -95,7 +125,30 @@ func newConnector(ctx context.Context, c *bsql.Config) (*Connector, error) {
 		return nil, err
 	}
 
-	celEnv, err := bcel.NewEnv(ctx)
+	err = c.ResolveQueries(dbEngine)
+	if err != nil {
+		return nil, err
+	}
+
+	var envOpts []bcel.EnvOption
+	if c.Normalize != nil && c.Normalize.CaseInsensitiveColumns {
+		envOpts = append(envOpts, bcel.WithCaseInsensitiveColumns())
+	}
+
+	var lookups *bsql.LookupTables
+	if len(c.Lookups) > 0 {
+		lookups, err = bsql.NewLookupTables(c, db, dbEngine)
+		if err != nil {
+			return nil, err
+		}
+		envOpts = append(envOpts, bcel.WithLookups(lookups))
+	}
+
+	if len(c.Functions) > 0 {
+		envOpts = append(envOpts, bcel.WithFunctions(c.Functions))
+	}
+
+	celEnv, err := bcel.NewEnv(ctx, envOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -105,5 +158,6 @@ func newConnector(ctx context.Context, c *bsql.Config) (*Connector, error) {
 		db:       db,
 		dbEngine: dbEngine,
 		celEnv:   celEnv,
+		lookups:  lookups,
 	}, nil
 }
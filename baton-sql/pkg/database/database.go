@@ -28,6 +28,32 @@ const (
 	Oracle
 )
 
+var engineNames = map[DbEngine]string{
+	MySQL:      "mysql",
+	PostgreSQL: "postgres",
+	SQLite:     "sqlite",
+	MSSQL:      "sqlserver",
+	Oracle:     "oracle",
+}
+
+// String returns the engine name, matching the DSN scheme used to connect to it.
+func (d DbEngine) String() string {
+	if name, ok := engineNames[d]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseDbEngine returns the engine for the given name. Names match the DSN schemes accepted by Connect.
+func ParseDbEngine(name string) (DbEngine, error) {
+	for engine, engineName := range engineNames {
+		if engineName == name {
+			return engine, nil
+		}
+	}
+	return Unknown, fmt.Errorf("unknown database engine: %s", name)
+}
+
 func updateFromEnv(dsn string) (string, error) {
 	var err error
 